@@ -0,0 +1,459 @@
+package osinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a parsed platform constraint expression, such as
+//
+//	ubuntu >= 20.04
+//	rhel-like && version >= 8
+//	darwin >= 12 && arch == arm64
+//	windows build >= 22000
+//	(debian || alpine) && !wsl
+//
+// An expression combines terms with &&, || and !, grouped by parentheses.
+// A term is one of:
+//   - FIELD OP VALUE, where FIELD is family, arch, id, name, codename,
+//     distro, version or build. Only version and build support <, <=, > and
+//     >=, the other fields only support == and !=.
+//   - OS, which matches the OS ID or Family (such as "ubuntu" or "linux").
+//     "X-like" matches the distribution X and its derivatives, and "wsl"
+//     matches WSL. Names that are neither a known ID nor a family are
+//     rejected, to catch typos.
+//   - OS OP VERSION, a shorthand for OS && version OP VERSION.
+//   - OS FIELD OP VALUE, a shorthand for OS && FIELD OP VALUE.
+//
+// Versions are compared using only the components present in the expression,
+// so "version == 8" matches 8.9 and "darwin >= 12" matches 12.6.
+// Values containing spaces can be double quoted: name == "Windows 11 Pro".
+type Constraint struct {
+	expression string
+	root       constraintNode
+}
+
+// ParseConstraint parses a constraint expression, returning an error that
+// points at the offending position if the syntax is invalid.
+func ParseConstraint(expression string) (*Constraint, error) {
+	tokens, err := tokenizeConstraint(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &constraintParser{expression: expression, tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.current(); token.kind != tokenEnd {
+		return nil, parser.errorAt(token, "unexpected [%v]", token.text)
+	}
+	return &Constraint{expression: expression, root: root}, nil
+}
+
+// Matches returns true if info satisfies the constraint.
+func (c *Constraint) Matches(info *OSInfo) bool {
+	return c.root.evaluate(info)
+}
+
+func (c *Constraint) String() string {
+	return c.expression
+}
+
+// Satisfies parses and evaluates a constraint expression against info.
+func (info *OSInfo) Satisfies(expression string) (bool, error) {
+	constraint, err := ParseConstraint(expression)
+	if err != nil {
+		return false, err
+	}
+	return constraint.Matches(info), nil
+}
+
+// ----------------------------------------------------------------------------
+// Evaluation
+
+type constraintNode interface {
+	evaluate(info *OSInfo) bool
+}
+
+type andNode struct{ left, right constraintNode }
+type orNode struct{ left, right constraintNode }
+type notNode struct{ operand constraintNode }
+
+// osNode matches an OS by name, such as "ubuntu", "linux" or "rhel-like"
+type osNode struct{ name string }
+
+type comparisonNode struct {
+	field    string
+	operator string
+	value    string
+}
+
+func (n andNode) evaluate(info *OSInfo) bool {
+	return n.left.evaluate(info) && n.right.evaluate(info)
+}
+
+func (n orNode) evaluate(info *OSInfo) bool {
+	return n.left.evaluate(info) || n.right.evaluate(info)
+}
+
+func (n notNode) evaluate(info *OSInfo) bool {
+	return !n.operand.evaluate(info)
+}
+
+func (n osNode) evaluate(info *OSInfo) bool {
+	if n.name == "wsl" {
+		return info.IsWSL
+	}
+	if like := strings.TrimSuffix(n.name, "-like"); like != n.name {
+		if strings.EqualFold(info.ID, like) || containsString(info.OSRelease.IDLike, like) {
+			return true
+		}
+		// Only families (such as "debian") match through DistroFamily, which
+		// would otherwise make "ubuntu-like" match Debian
+		family, ok := distroFamilies[like]
+		return ok && family == like && info.DistroFamily == family
+	}
+	return strings.EqualFold(info.ID, n.name) || strings.EqualFold(info.Family, n.name)
+}
+
+func (n comparisonNode) evaluate(info *OSInfo) bool {
+	var result int
+	switch n.field {
+	case "version":
+		actual, err := info.ParsedVersion()
+		if err != nil {
+			return false
+		}
+		if result, err = compareVersionPrefix(actual, n.value); err != nil {
+			return false
+		}
+	case "build":
+		actual, actualErr := strconv.Atoi(info.Build)
+		wanted, wantedErr := strconv.Atoi(n.value)
+		if actualErr == nil && wantedErr == nil {
+			result = compareInts(actual, wanted)
+		} else {
+			result = strings.Compare(info.Build, n.value)
+		}
+	default:
+		if strings.EqualFold(constraintStringField(info, n.field), n.value) {
+			result = 0
+		} else {
+			result = 1
+		}
+	}
+
+	switch n.operator {
+	case "==":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	default:
+		return result >= 0
+	}
+}
+
+// Fields that can be compared, and whether they support ordering
+var constraintFields = map[string]bool{
+	"family":   false,
+	"arch":     false,
+	"id":       false,
+	"name":     false,
+	"codename": false,
+	"distro":   false,
+	"version":  true,
+	"build":    true,
+}
+
+// OS names that constraints accept besides distributions: families (GOOS
+// values) and "wsl"
+var constraintOSNames = []string{
+	"linux", "windows", "darwin", "freebsd", "openbsd", "netbsd", "dragonfly",
+	"solaris", "illumos", "aix", "android", "ios",
+	"wsl",
+}
+
+// The IDs of distributions that don't belong to a family in distroFamilies
+var constraintDistroIDs = []string{
+	"nixos", "void", "slackware", "openwrt", "clear-linux-os", "photon",
+	"mariner", "azurelinux", "flatcar", "rhcos", "openeuler", "mageia", "solus",
+	"guix", "wolfi",
+}
+
+func isConstraintDistroID(name string) bool {
+	if _, ok := distroFamilies[name]; ok {
+		return true
+	}
+	return containsString(constraintDistroIDs, name)
+}
+
+// isConstraintOSName returns true for the OS names that constraints accept.
+// Only distributions have derivatives, so "linux-like" is rejected.
+func isConstraintOSName(name string) bool {
+	if like := strings.TrimSuffix(name, "-like"); like != name {
+		return isConstraintDistroID(like)
+	}
+	return isConstraintDistroID(name) || containsString(constraintOSNames, name)
+}
+
+func constraintStringField(info *OSInfo, field string) string {
+	switch field {
+	case "family":
+		return info.Family
+	case "arch":
+		return info.Architecture
+	case "id":
+		return info.ID
+	case "name":
+		return info.Name
+	case "codename":
+		return info.Codename
+	default:
+		return info.DistroFamily
+	}
+}
+
+// compareVersionPrefix compares only the components present in wanted, so
+// that 8.9 is equal to 8 but greater than 8.8. The suffix is compared when
+// wanted has one, so that 12.0-RELEASE-p9 is lower than 12.0-RELEASE-p10.
+func compareVersionPrefix(actual Version, wanted string) (int, error) {
+	wantedVersion, err := ParseVersion(wanted)
+	if err != nil {
+		return 0, err
+	}
+	found := versionRE.FindStringSubmatch(strings.TrimSpace(wanted))
+	pairs := [][2]int{{actual.Major, wantedVersion.Major}, {actual.Minor, wantedVersion.Minor}, {actual.Patch, wantedVersion.Patch}}
+	for i, pair := range pairs {
+		if found[i+1] == "" {
+			break
+		}
+		if result := compareInts(pair[0], pair[1]); result != 0 {
+			return result, nil
+		}
+	}
+	if wantedVersion.Suffix == "" {
+		return 0, nil
+	}
+	return compareVersionSuffixes(actual.Suffix, wantedVersion.Suffix), nil
+}
+
+// ----------------------------------------------------------------------------
+// Parsing
+
+type constraintTokenKind int
+
+const (
+	tokenEnd constraintTokenKind = iota
+	tokenWord
+	tokenString
+	tokenComparison
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type constraintToken struct {
+	kind     constraintTokenKind
+	text     string
+	position int
+}
+
+func isConstraintWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("._-+~/", c) >= 0
+}
+
+func tokenizeConstraint(expression string) (tokens []constraintToken, err error) {
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+			continue
+		case isConstraintWordChar(c):
+			for i < len(expression) && isConstraintWordChar(expression[i]) {
+				i++
+			}
+			tokens = append(tokens, constraintToken{tokenWord, expression[start:i], start})
+			continue
+		case c == '"':
+			end := strings.IndexByte(expression[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("Syntax error at position %v in [%v]: unterminated string", start+1, expression)
+			}
+			i += end + 2
+			tokens = append(tokens, constraintToken{tokenString, expression[start+1 : i-1], start})
+			continue
+		case c == '(':
+			tokens = append(tokens, constraintToken{tokenOpen, "(", start})
+			i++
+			continue
+		case c == ')':
+			tokens = append(tokens, constraintToken{tokenClose, ")", start})
+			i++
+			continue
+		}
+
+		var kind constraintTokenKind
+		var text string
+		for _, candidate := range []struct {
+			text string
+			kind constraintTokenKind
+		}{
+			{"&&", tokenAnd}, {"||", tokenOr},
+			{"==", tokenComparison}, {"!=", tokenComparison},
+			{">=", tokenComparison}, {"<=", tokenComparison},
+			{">", tokenComparison}, {"<", tokenComparison},
+			{"!", tokenNot},
+		} {
+			if strings.HasPrefix(expression[i:], candidate.text) {
+				kind, text = candidate.kind, candidate.text
+				break
+			}
+		}
+		if text == "" {
+			return nil, fmt.Errorf("Syntax error at position %v in [%v]: unexpected character [%c]", start+1, expression, c)
+		}
+		tokens = append(tokens, constraintToken{kind, text, start})
+		i += len(text)
+	}
+
+	tokens = append(tokens, constraintToken{tokenEnd, "end of expression", len(expression)})
+	return
+}
+
+type constraintParser struct {
+	expression string
+	tokens     []constraintToken
+	index      int
+}
+
+func (p *constraintParser) current() constraintToken {
+	return p.tokens[p.index]
+}
+
+func (p *constraintParser) peek() constraintToken {
+	if p.index+1 < len(p.tokens) {
+		return p.tokens[p.index+1]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *constraintParser) advance() constraintToken {
+	token := p.tokens[p.index]
+	if token.kind != tokenEnd {
+		p.index++
+	}
+	return token
+}
+
+func (p *constraintParser) errorAt(token constraintToken, format string, args ...interface{}) error {
+	return fmt.Errorf("Syntax error at position %v in [%v]: %v", token.position+1, p.expression, fmt.Sprintf(format, args...))
+}
+
+func (p *constraintParser) parseOr() (constraintNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.current().kind == tokenOr {
+		p.advance()
+		var right constraintNode
+		if right, err = p.parseAnd(); err == nil {
+			left = orNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *constraintParser) parseAnd() (constraintNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.current().kind == tokenAnd {
+		p.advance()
+		var right constraintNode
+		if right, err = p.parseUnary(); err == nil {
+			left = andNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *constraintParser) parseUnary() (constraintNode, error) {
+	if p.current().kind == tokenNot {
+		p.advance()
+		operand, err := p.parseUnary()
+		return notNode{operand}, err
+	}
+	return p.parsePrimary()
+}
+
+func (p *constraintParser) parsePrimary() (constraintNode, error) {
+	token := p.advance()
+	switch token.kind {
+	case tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenClose {
+			return nil, p.errorAt(closing, "expected [)] but got [%v]", closing.text)
+		}
+		return node, nil
+	case tokenWord:
+		name := strings.ToLower(token.text)
+		if _, isField := constraintFields[name]; isField {
+			return p.parseComparison(token, name)
+		}
+
+		if !isConstraintOSName(name) {
+			return nil, p.errorAt(token, "unknown OS [%v]", token.text)
+		}
+		target := osNode{name}
+		next := p.current()
+		if next.kind == tokenComparison {
+			comparison, err := p.parseComparison(token, "version")
+			return andNode{target, comparison}, err
+		}
+		if field := strings.ToLower(next.text); next.kind == tokenWord && p.peek().kind == tokenComparison {
+			if _, isField := constraintFields[field]; !isField {
+				return nil, p.errorAt(next, "unknown field [%v]", next.text)
+			}
+			p.advance()
+			comparison, err := p.parseComparison(next, field)
+			return andNode{target, comparison}, err
+		}
+		return target, nil
+	default:
+		return nil, p.errorAt(token, "expected a field, an OS name or [(] but got [%v]", token.text)
+	}
+}
+
+func (p *constraintParser) parseComparison(fieldToken constraintToken, field string) (constraintNode, error) {
+	operator := p.advance()
+	if operator.kind != tokenComparison {
+		return nil, p.errorAt(operator, "expected a comparison after [%v] but got [%v]", fieldToken.text, operator.text)
+	}
+	if operator.text != "==" && operator.text != "!=" && !constraintFields[field] {
+		return nil, p.errorAt(operator, "[%v] only supports == and !=", field)
+	}
+
+	value := p.advance()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, p.errorAt(value, "expected a value after [%v] but got [%v]", operator.text, value.text)
+	}
+	if field == "version" {
+		if _, err := ParseVersion(value.text); err != nil {
+			return nil, p.errorAt(value, "[%v] is not a version", value.text)
+		}
+	}
+	return comparisonNode{field, operator.text, value.text}, nil
+}
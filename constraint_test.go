@@ -0,0 +1,174 @@
+package osinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+var constraintUbuntu = &OSInfo{
+	Family:       "linux",
+	Architecture: "amd64",
+	ID:           "ubuntu",
+	Name:         "Ubuntu",
+	Codename:     "jammy",
+	Version:      "22.04",
+	PointVersion: "22.04.3",
+	DistroFamily: DistroFamilyDebian,
+	OSRelease:    OSRelease{IDLike: []string{"debian"}},
+}
+
+var constraintDebian = &OSInfo{
+	Family:       "linux",
+	Architecture: "amd64",
+	ID:           "debian",
+	Name:         "Debian GNU/Linux",
+	Codename:     "bookworm",
+	Version:      "12",
+	PointVersion: "12.5",
+	DistroFamily: DistroFamilyDebian,
+}
+
+var constraintRocky = &OSInfo{
+	Family:       "linux",
+	Architecture: "arm64",
+	ID:           "rocky",
+	Name:         "Rocky Linux",
+	Version:      "8.9",
+	DistroFamily: DistroFamilyRHEL,
+	OSRelease:    OSRelease{IDLike: []string{"rhel", "centos", "fedora"}},
+}
+
+var constraintMac = &OSInfo{
+	Family:       "darwin",
+	Architecture: "arm64",
+	ID:           "darwin",
+	Name:         "macOS",
+	Version:      "12.6",
+	Build:        "21G115",
+}
+
+var constraintWindows = &OSInfo{
+	Family:       "windows",
+	Architecture: "amd64",
+	ID:           "windows",
+	Name:         "Windows 11 Pro",
+	Version:      "10.0",
+	Build:        "22631",
+}
+
+func expectConstraint(t *testing.T, expected bool, expression string, info *OSInfo) {
+	constraint, err := ParseConstraint(expression)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if actual := constraint.Matches(info); actual != expected {
+		t.Errorf("Expected [%v] to be %v for %v %v but got %v", expression, expected, info.ID, info.Version, actual)
+	}
+}
+
+func expectConstraintError(t *testing.T, expression string, expected string) {
+	_, err := ParseConstraint(expression)
+	if err == nil {
+		t.Errorf("Expected an error parsing [%v]", expression)
+		return
+	}
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected [%v] in error [%v]", expected, err)
+	}
+}
+
+func TestConstraintOSVersion(t *testing.T) {
+	expectConstraint(t, true, "ubuntu >= 20.04", constraintUbuntu)
+	expectConstraint(t, true, "ubuntu >= 22.04.3", constraintUbuntu)
+	expectConstraint(t, false, "ubuntu >= 22.10", constraintUbuntu)
+	expectConstraint(t, false, "ubuntu >= 20.04", constraintRocky)
+	expectConstraint(t, true, "ubuntu == 22.04", constraintUbuntu)
+	expectConstraint(t, true, "ubuntu < 24.04", constraintUbuntu)
+	expectConstraint(t, true, "darwin >= 12", constraintMac)
+	expectConstraint(t, false, "darwin > 12", constraintMac)
+	expectConstraint(t, true, "darwin >= 12 && arch == arm64", constraintMac)
+	expectConstraint(t, false, "darwin >= 12 && arch == amd64", constraintMac)
+}
+
+func TestConstraintLike(t *testing.T) {
+	expectConstraint(t, true, "rhel-like && version >= 8", constraintRocky)
+	expectConstraint(t, false, "rhel-like && version >= 9", constraintRocky)
+	expectConstraint(t, true, "version == 8", constraintRocky)
+	expectConstraint(t, true, "centos-like", constraintRocky)
+	expectConstraint(t, false, "rhel-like", constraintUbuntu)
+	expectConstraint(t, true, "debian-like", constraintUbuntu)
+	expectConstraint(t, true, "ubuntu-like", constraintUbuntu)
+	expectConstraint(t, false, "ubuntu-like", constraintDebian)
+	expectConstraint(t, false, "kali-like", constraintDebian)
+	expectConstraint(t, true, "debian-like", constraintDebian)
+	// Derivatives without ID_LIKE are matched through their family
+	expectConstraint(t, true, "rhel-like", &OSInfo{Family: "linux", ID: "amzn", Version: "2023", DistroFamily: DistroFamilyRHEL})
+	expectConstraint(t, true, "distro == debian", constraintUbuntu)
+}
+
+func TestConstraintVersionSuffix(t *testing.T) {
+	patched := &OSInfo{Family: "freebsd", ID: "freebsd", Version: "12.0-RELEASE-p9"}
+	expectConstraint(t, false, "freebsd version >= 12.0-RELEASE-p10", patched)
+	expectConstraint(t, true, "freebsd version >= 12.0-RELEASE-p4", patched)
+	expectConstraint(t, true, "freebsd version >= 12.0-RELEASE", patched)
+	expectConstraint(t, true, "freebsd version >= 12.0-RC1", patched)
+	expectConstraint(t, true, "freebsd version == 12.0", patched)
+
+	candidate := &OSInfo{Family: "freebsd", ID: "freebsd", Version: "14.0-RC1"}
+	expectConstraint(t, false, "freebsd version >= 14.0-RELEASE", candidate)
+	expectConstraint(t, true, "freebsd version < 14.0-RELEASE", candidate)
+	expectConstraint(t, true, "freebsd version >= 14.0-BETA2", candidate)
+	expectConstraint(t, true, "freebsd >= 13.2-RELEASE", candidate)
+}
+
+func TestConstraintWindowsBuild(t *testing.T) {
+	expectConstraint(t, true, "windows build >= 22000", constraintWindows)
+	expectConstraint(t, false, "windows build >= 26100", constraintWindows)
+	expectConstraint(t, false, "windows build >= 22000", constraintUbuntu)
+	expectConstraint(t, true, "windows >= 10.0.22000", constraintWindows)
+	expectConstraint(t, true, `name == "Windows 11 Pro"`, constraintWindows)
+	expectConstraint(t, true, "darwin build == 21G115", constraintMac)
+}
+
+func TestConstraintBooleanLogic(t *testing.T) {
+	expectConstraint(t, true, "linux", constraintUbuntu)
+	expectConstraint(t, false, "linux", constraintMac)
+	expectConstraint(t, true, "!windows", constraintUbuntu)
+	expectConstraint(t, true, "ubuntu || darwin", constraintMac)
+	expectConstraint(t, true, "(ubuntu || rocky) && arch == arm64", constraintRocky)
+	expectConstraint(t, false, "(ubuntu || rocky) && arch == arm64", constraintUbuntu)
+	expectConstraint(t, true, "ubuntu || rocky && arch == arm64", constraintUbuntu)
+	expectConstraint(t, true, "linux && !wsl", constraintUbuntu)
+	expectConstraint(t, true, "family != windows", constraintMac)
+	expectConstraint(t, true, "ID == UBUNTU", constraintUbuntu)
+}
+
+func TestConstraintSyntaxErrors(t *testing.T) {
+	expectConstraintError(t, "", "position 1 in []: expected a field, an OS name or [(] but got [end of expression]")
+	expectConstraintError(t, "ubuntu >=", "position 10 in [ubuntu >=]: expected a value after [>=]")
+	expectConstraintError(t, "ubuntu >= jammy", "position 11 in [ubuntu >= jammy]: [jammy] is not a version")
+	expectConstraintError(t, "arch >= arm64", "position 6 in [arch >= arm64]: [arch] only supports == and !=")
+	expectConstraintError(t, "(ubuntu", "position 8 in [(ubuntu]: expected [)] but got [end of expression]")
+	expectConstraintError(t, "ubuntu ubuntu", "position 8 in [ubuntu ubuntu]: unexpected [ubuntu]")
+	expectConstraintError(t, "windows colour >= 1", "position 9 in [windows colour >= 1]: unknown field [colour]")
+	expectConstraintError(t, "ubunto >= 20.04", "position 1 in [ubunto >= 20.04]: unknown OS [ubunto]")
+	expectConstraintError(t, "linux && !wls", "position 11 in [linux && !wls]: unknown OS [wls]")
+	expectConstraintError(t, "redhat-like", "position 1 in [redhat-like]: unknown OS [redhat-like]")
+	expectConstraintError(t, "linux-like", "position 1 in [linux-like]: unknown OS [linux-like]")
+	expectConstraintError(t, "windows-like", "position 1 in [windows-like]: unknown OS [windows-like]")
+	expectConstraintError(t, "!wsl-like", "position 2 in [!wsl-like]: unknown OS [wsl-like]")
+	expectConstraintError(t, "ubuntu & darwin", "position 8 in [ubuntu & darwin]: unexpected character [&]")
+	expectConstraintError(t, `name == "open`, "position 9 in [name == \"open]: unterminated string")
+	expectConstraintError(t, "version", "position 8 in [version]: expected a comparison after [version]")
+}
+
+func TestSatisfies(t *testing.T) {
+	ok, err := constraintUbuntu.Satisfies("ubuntu >= 20.04")
+	if err != nil || !ok {
+		t.Errorf("Expected a match, got %v %v", ok, err)
+	}
+	if _, err = constraintUbuntu.Satisfies("ubuntu >="); err == nil {
+		t.Error("Expected a syntax error")
+	}
+}
@@ -0,0 +1,255 @@
+package osinfo
+
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// Values of Container.Runtime. Other values of the container environment
+// variable (such as "lxc-libvirt") are reported as they are.
+const (
+	ContainerRuntimeDocker        = "docker"
+	ContainerRuntimePodman        = "podman"
+	ContainerRuntimeLXC           = "lxc"
+	ContainerRuntimeSystemdNspawn = "systemd-nspawn"
+	ContainerRuntimeContainerd    = "containerd"
+	ContainerRuntimeCRIO          = "cri-o"
+	ContainerRuntimeOpenVZ        = "openvz"
+	// In a container, but the runtime couldn't be identified
+	ContainerRuntimeOther = "container-other"
+)
+
+// Container describes the container the OS runs in, if any.
+type Container struct {
+	IsContainer bool
+	// One of the ContainerRuntime constants, or empty outside of a container
+	Runtime string
+	// The container ID (the 64 hex digit ID for Docker, Podman, containerd and
+	// CRI-O, the container name for LXC), if it could be found. In a
+	// Kubernetes pod it comes from the cgroup only: the files mounted into the
+	// container are the pod sandbox's.
+	ID string
+	// The contents of /run/.containerenv, such as "engine", "name" and
+	// "image" (Podman only)
+	Fields map[string]string
+	// What was found, such as "/.dockerenv exists" or
+	// "/proc/self/cgroup: /docker/0123..."
+	Evidence []string
+}
+
+// What a single source says about the container
+type containerFinding struct {
+	runtime  string
+	id       string
+	evidence string
+}
+
+var containerIDRE = regexp.MustCompile(`^(?:(docker|libpod|crio|cri-containerd)-)?([0-9a-f]{64})(?:\.scope)?$`)
+
+// Prefixes of systemd scopes and cgroupfs directories named after the
+// container ID
+var cgroupContainerPrefixes = map[string]string{
+	"docker":         ContainerRuntimeDocker,
+	"libpod":         ContainerRuntimePodman,
+	"crio":           ContainerRuntimeCRIO,
+	"cri-containerd": ContainerRuntimeContainerd,
+}
+
+// parseContainerCgroup finds the container in /proc/self/cgroup, which has
+// a "hierarchy:controllers:path" line per cgroup v1 hierarchy, and a single
+// "0::path" line with cgroup v2:
+//
+//	12:memory:/docker/3f4e...
+//	0::/system.slice/docker-3f4e....scope
+//	0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod1a2b....slice/cri-containerd-3f4e....scope
+//
+// With a cgroup namespace (the default with cgroup v2), the path is just "/".
+func parseContainerCgroup(contents string) (finding containerFinding, found bool) {
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		path := fields[2]
+		components := strings.Split(path, "/")
+		for i := len(components) - 1; i > 0; i-- {
+			component, parent := components[i], components[i-1]
+			if match := containerIDRE.FindStringSubmatch(component); match != nil {
+				finding.id = match[2]
+				finding.runtime = cgroupContainerPrefixes[match[1]]
+				if match[1] == "" && parent == "docker" {
+					finding.runtime = ContainerRuntimeDocker
+				}
+			} else if strings.HasPrefix(component, "lxc.payload.") {
+				finding.runtime = ContainerRuntimeLXC
+				finding.id = strings.TrimPrefix(component, "lxc.payload.")
+			} else if component != "" && (parent == "lxc" || parent == "lxc.payload") {
+				finding.runtime = ContainerRuntimeLXC
+				finding.id = component
+			} else {
+				continue
+			}
+			finding.evidence = "/proc/self/cgroup: " + path
+			return finding, true
+		}
+	}
+	return
+}
+
+var mountInfoContainerRE = regexp.MustCompile(`/(containers|overlay-containers)/([0-9a-f]{64})/`)
+
+// The files that runtimes bind mount into containers
+var containerMountPoints = []string{"/etc/hostname", "/etc/hosts", "/etc/resolv.conf"}
+
+// parseContainerMountInfo finds the runtime in the source of the files it
+// bind mounts into the container, which also works with a cgroup namespace.
+// /proc/self/mountinfo has the source path relative to its filesystem in the
+// fourth field and the mount point in the fifth:
+//
+//	712 698 254:1 /var/lib/docker/containers/3f4e.../hostname /etc/hostname rw,relatime - ext4 /dev/vda1 rw
+//	834 821 0:26 /containers/storage/overlay-containers/5b7c.../userdata/resolv.conf /etc/resolv.conf rw - tmpfs tmpfs rw
+//	835 821 252:1 /var/lib/kubelet/pods/1a2b.../etc-hosts /etc/hosts rw - ext4 /dev/vda1 rw
+//
+// Docker's path has the container ID. CRI-O's, and Docker's under dockershim
+// (where kubelet manages /etc/hosts), have the ID of the pod's sandbox (pause)
+// container instead, which isn't reported.
+func parseContainerMountInfo(contents string) (finding containerFinding, found bool) {
+	inPod := false
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !containsString(containerMountPoints, fields[4]) {
+			continue
+		}
+		if strings.Contains(fields[3], "/kubelet/pods/") {
+			inPod = true
+		}
+		match := mountInfoContainerRE.FindStringSubmatch(fields[3])
+		if match == nil || found {
+			continue
+		}
+		// Podman also uses containers/storage, but is identified by
+		// /run/.containerenv first
+		finding.runtime = ContainerRuntimeCRIO
+		if match[1] == "containers" {
+			finding.runtime = ContainerRuntimeDocker
+			finding.id = match[2]
+		}
+		finding.evidence = "/proc/self/mountinfo: " + fields[3]
+		found = true
+	}
+	if inPod {
+		finding.id = ""
+	}
+	return
+}
+
+// readEnvironmentVariable reads a variable from a NUL separated environment,
+// such as /proc/1/environ.
+func readEnvironmentVariable(fsys fs.FS, path string, name string) string {
+	environ, err := readTextFile(fsys, path)
+	if err != nil {
+		return ""
+	}
+	for _, variable := range strings.Split(environ, "\x00") {
+		if strings.HasPrefix(variable, name+"=") {
+			return strings.TrimPrefix(variable, name+"=")
+		}
+	}
+	return ""
+}
+
+func exists(fsys fs.FS, path string) bool {
+	_, err := fs.Stat(fsys, path)
+	return err == nil
+}
+
+// The container variable is set by systemd-nspawn, Podman and LXC, and by
+// some images (to "oci")
+func containerRuntimeFromVariable(value string) string {
+	if value == "oci" {
+		return ContainerRuntimeOther
+	}
+	return value
+}
+
+// detectContainer checks the files that container runtimes create, the
+// container variable (in PID 1's environment, or containerEnv when it's
+// given), and the cgroup and mount paths, which also have the container ID.
+func detectContainer(fsys fs.FS, containerEnv string) (container Container) {
+	var findings []containerFinding
+
+	// /proc/vz is also present on the host, which has /proc/bc
+	if exists(fsys, "proc/vz") && !exists(fsys, "proc/bc") {
+		findings = append(findings, containerFinding{runtime: ContainerRuntimeOpenVZ, evidence: "/proc/vz exists"})
+	}
+
+	if containerenv, err := readTextFile(fsys, "run/.containerenv"); err == nil {
+		// Malformed lines are skipped
+		container.Fields, _ = parseShellAssignments(containerenv)
+		findings = append(findings, containerFinding{
+			runtime:  ContainerRuntimePodman,
+			id:       container.Fields["id"],
+			evidence: "/run/.containerenv exists",
+		})
+	}
+
+	if exists(fsys, ".dockerenv") {
+		findings = append(findings, containerFinding{runtime: ContainerRuntimeDocker, evidence: "/.dockerenv exists"})
+	}
+
+	// Written by systemd when it runs as PID 1 in a container
+	if value := readSysFile(fsys, "run/systemd/container"); value != "" {
+		findings = append(findings, containerFinding{
+			runtime:  containerRuntimeFromVariable(value),
+			evidence: "/run/systemd/container: " + value,
+		})
+	}
+
+	// PID 1's environment is only readable by root
+	if value := readEnvironmentVariable(fsys, "proc/1/environ", "container"); value != "" {
+		findings = append(findings, containerFinding{
+			runtime:  containerRuntimeFromVariable(value),
+			evidence: "/proc/1/environ: container=" + value,
+		})
+	} else if containerEnv != "" {
+		findings = append(findings, containerFinding{
+			runtime:  containerRuntimeFromVariable(containerEnv),
+			evidence: "container environment variable: " + containerEnv,
+		})
+	}
+
+	if cgroup, err := readTextFile(fsys, "proc/self/cgroup"); err == nil {
+		if finding, found := parseContainerCgroup(cgroup); found {
+			findings = append(findings, finding)
+		}
+	}
+
+	if mountInfo, err := readTextFile(fsys, "proc/self/mountinfo"); err == nil {
+		if finding, found := parseContainerMountInfo(mountInfo); found {
+			findings = append(findings, finding)
+		}
+	}
+
+	if exists(fsys, "dev/lxd/sock") {
+		findings = append(findings, containerFinding{runtime: ContainerRuntimeLXC, evidence: "/dev/lxd/sock exists"})
+	}
+
+	// The first source to name the runtime wins, and the first to have an ID
+	for _, finding := range findings {
+		container.IsContainer = true
+		container.Evidence = append(container.Evidence, finding.evidence)
+		if container.Runtime == "" || container.Runtime == ContainerRuntimeOther {
+			if finding.runtime != "" {
+				container.Runtime = finding.runtime
+			}
+		}
+		if container.ID == "" {
+			container.ID = finding.id
+		}
+	}
+	if container.IsContainer && container.Runtime == "" {
+		container.Runtime = ContainerRuntimeOther
+	}
+	return
+}
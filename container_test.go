@@ -0,0 +1,180 @@
+package osinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	dockerContainerID     = "3f4e8a1c9b2d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f"
+	podmanContainerID     = "9c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d"
+	kubernetesContainerID = "e0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f"
+	kubernetesSandboxID   = "7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b"
+)
+
+func expectContainer(t *testing.T, files map[string]string, containerEnv string, runtime, id string, evidence ...string) Container {
+	container := detectContainer(legacyFS(files), containerEnv)
+	if container.IsContainer != (runtime != "") {
+		t.Errorf("Expected IsContainer to be %v for [%v]", runtime != "", runtime)
+	}
+	expectEqualStrings(t, runtime, container.Runtime)
+	expectEqualStrings(t, id, container.ID)
+	expectEqualStrings(t, strings.Join(evidence, "; "), strings.Join(container.Evidence, "; "))
+	return container
+}
+
+func TestDetectContainerDocker(t *testing.T) {
+	// cgroup v1
+	expectContainer(t, map[string]string{
+		".dockerenv":       "",
+		"proc/self/cgroup": string(readFixture(t, "cgroup_docker_v1.txt")),
+	}, "", ContainerRuntimeDocker, dockerContainerID,
+		"/.dockerenv exists", "/proc/self/cgroup: /docker/"+dockerContainerID)
+
+	// cgroup v2 with a cgroup namespace, where only mountinfo has the ID
+	expectContainer(t, map[string]string{
+		".dockerenv":          "",
+		"proc/self/cgroup":    "0::/\n",
+		"proc/self/mountinfo": string(readFixture(t, "mountinfo_docker_cgroupns.txt")),
+	}, "", ContainerRuntimeDocker, dockerContainerID,
+		"/.dockerenv exists", "/proc/self/mountinfo: /var/lib/docker/containers/"+dockerContainerID+"/resolv.conf")
+
+	// cgroup v2 without a namespace, using the systemd cgroup driver
+	expectContainer(t, map[string]string{
+		"proc/self/cgroup": "0::/system.slice/docker-" + dockerContainerID + ".scope\n",
+	}, "", ContainerRuntimeDocker, dockerContainerID,
+		"/proc/self/cgroup: /system.slice/docker-"+dockerContainerID+".scope")
+}
+
+func TestDetectContainerPodman(t *testing.T) {
+	container := expectContainer(t, map[string]string{
+		"run/.containerenv": string(readFixture(t, "containerenv_podman.txt")),
+		"proc/1/environ":    "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin\x00container=podman\x00HOME=/root\x00",
+		"proc/self/cgroup":  "0::/\n",
+	}, "", ContainerRuntimePodman, podmanContainerID,
+		"/run/.containerenv exists", "/proc/1/environ: container=podman")
+	expectEqualStrings(t, "podman-4.9.3", container.Fields["engine"])
+	expectEqualStrings(t, "web", container.Fields["name"])
+	expectEqualStrings(t, "docker.io/library/nginx:1.25", container.Fields["image"])
+	expectEqualStrings(t, "1", container.Fields["rootless"])
+
+	// Rootless with --privileged, or older versions, leave .containerenv empty
+	container = expectContainer(t, map[string]string{
+		"run/.containerenv": "",
+		"proc/self/cgroup":  "0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + podmanContainerID + ".scope/container\n",
+	}, "", ContainerRuntimePodman, podmanContainerID,
+		"/run/.containerenv exists",
+		"/proc/self/cgroup: /user.slice/user-1000.slice/user@1000.service/user.slice/libpod-"+podmanContainerID+".scope/container")
+	expectEqualInts(t, 0, len(container.Fields))
+}
+
+func TestDetectContainerKubernetes(t *testing.T) {
+	expectContainer(t, map[string]string{
+		"proc/self/cgroup": string(readFixture(t, "cgroup_kubernetes_containerd_v2.txt")),
+	}, "", ContainerRuntimeContainerd, kubernetesContainerID,
+		"/proc/self/cgroup: /kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod5b7c1d9e_2f3a_4b5c_8d6e_7f8091a2b3c4.slice/cri-containerd-"+kubernetesContainerID+".scope")
+
+	expectContainer(t, map[string]string{
+		"proc/self/cgroup": string(readFixture(t, "cgroup_kubernetes_crio_v1.txt")),
+	}, "", ContainerRuntimeCRIO, kubernetesContainerID,
+		"/proc/self/cgroup: /kubepods/burstable/pod5b7c1d9e-2f3a-4b5c-8d6e-7f8091a2b3c4/crio-"+kubernetesContainerID)
+
+	// CRI-O with a cgroup namespace, in a UBI image which sets container=oci.
+	// The mounts are the sandbox's, so there's no container ID.
+	expectContainer(t, map[string]string{
+		"proc/1/environ":      "container=oci\x00",
+		"proc/self/cgroup":    "0::/\n",
+		"proc/self/mountinfo": string(readFixture(t, "mountinfo_kubernetes_crio.txt")),
+	}, "", ContainerRuntimeCRIO, "",
+		"/proc/1/environ: container=oci",
+		"/proc/self/mountinfo: /containers/storage/overlay-containers/"+kubernetesSandboxID+"/userdata/resolv.conf")
+	// Without a namespace, the cgroup still has the container's own ID
+	expectContainer(t, map[string]string{
+		"proc/self/cgroup":    string(readFixture(t, "cgroup_kubernetes_crio_v1.txt")),
+		"proc/self/mountinfo": string(readFixture(t, "mountinfo_kubernetes_crio.txt")),
+	}, "", ContainerRuntimeCRIO, kubernetesContainerID,
+		"/proc/self/cgroup: /kubepods/burstable/pod5b7c1d9e-2f3a-4b5c-8d6e-7f8091a2b3c4/crio-"+kubernetesContainerID,
+		"/proc/self/mountinfo: /containers/storage/overlay-containers/"+kubernetesSandboxID+"/userdata/resolv.conf")
+
+	// dockershim also mounts the sandbox's files, with kubelet's /etc/hosts
+	expectContainer(t, map[string]string{
+		"proc/self/cgroup": "0::/\n",
+		"proc/self/mountinfo": "689 681 254:1 /var/lib/docker/containers/" + kubernetesSandboxID + "/resolv.conf /etc/resolv.conf rw,relatime - ext4 /dev/vda1 rw\n" +
+			"690 681 254:1 /var/lib/kubelet/pods/5b7c1d9e-2f3a-4b5c-8d6e-7f8091a2b3c4/etc-hosts /etc/hosts rw,relatime - ext4 /dev/vda1 rw\n",
+	}, "", ContainerRuntimeDocker, "",
+		"/proc/self/mountinfo: /var/lib/docker/containers/"+kubernetesSandboxID+"/resolv.conf")
+
+	// The cgroupfs driver doesn't name the runtime
+	expectContainer(t, map[string]string{
+		"proc/self/cgroup": string(readFixture(t, "cgroup_kubernetes_cgroupfs_v1.txt")),
+	}, "", ContainerRuntimeOther, kubernetesContainerID,
+		"/proc/self/cgroup: /kubepods/besteffort/pod5b7c1d9e-2f3a-4b5c-8d6e-7f8091a2b3c4/"+kubernetesContainerID)
+}
+
+func TestDetectContainerLXC(t *testing.T) {
+	expectContainer(t, map[string]string{
+		"proc/1/environ":   "container=lxc\x00",
+		"proc/self/cgroup": "0::/lxc.payload.web01/init.scope\n",
+	}, "", ContainerRuntimeLXC, "web01",
+		"/proc/1/environ: container=lxc", "/proc/self/cgroup: /lxc.payload.web01/init.scope")
+
+	// Older LXC with cgroup v1
+	expectContainer(t, map[string]string{
+		"proc/self/cgroup": "4:memory:/lxc/web01\n1:name=systemd:/lxc/web01\n",
+	}, "", ContainerRuntimeLXC, "web01",
+		"/proc/self/cgroup: /lxc/web01")
+
+	// LXD, where PID 1's environment isn't readable
+	expectContainer(t, map[string]string{
+		"dev/lxd/sock":     "",
+		"proc/self/cgroup": "0::/\n",
+	}, "lxc", ContainerRuntimeLXC, "",
+		"container environment variable: lxc", "/dev/lxd/sock exists")
+}
+
+func TestDetectContainerOthers(t *testing.T) {
+	expectContainer(t, map[string]string{
+		"run/systemd/container": "systemd-nspawn\n",
+		"proc/self/cgroup":      "0::/init.scope\n",
+	}, "", ContainerRuntimeSystemdNspawn, "",
+		"/run/systemd/container: systemd-nspawn")
+
+	expectContainer(t, map[string]string{
+		"proc/vz/veinfo": "",
+	}, "", ContainerRuntimeOpenVZ, "",
+		"/proc/vz exists")
+	// The OpenVZ host also has /proc/bc
+	expectContainer(t, map[string]string{
+		"proc/vz/veinfo":    "",
+		"proc/bc/0/meminfo": "",
+	}, "", "", "")
+
+	expectContainer(t, map[string]string{}, "oci", ContainerRuntimeOther, "",
+		"container environment variable: oci")
+}
+
+func TestDetectContainerHost(t *testing.T) {
+	expectContainer(t, map[string]string{
+		"proc/1/environ":      "HOME=/\x00TERM=linux\x00",
+		"proc/self/cgroup":    "0::/user.slice/user-1000.slice/session-3.scope\n",
+		"proc/self/mountinfo": "22 1 254:1 / / rw,relatime shared:1 - ext4 /dev/vda1 rw\n",
+	}, "", "", "")
+	expectContainer(t, map[string]string{}, "", "", "")
+}
+
+func TestGetOSInfoFromFSContainer(t *testing.T) {
+	// An exported container or a mounted root isn't running in a container
+	info, err := GetOSInfoFromFS(legacyFS(map[string]string{
+		"etc/os-release":   "ID=alpine\nVERSION_ID=3.19.1\n",
+		".dockerenv":       "",
+		"proc/self/cgroup": string(readFixture(t, "cgroup_docker_v1.txt")),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Container.IsContainer {
+		t.Errorf("Expected no container for another root")
+	}
+	expectEqualStrings(t, "", info.Container.Runtime)
+	expectEqualStrings(t, "", info.Container.ID)
+}
@@ -0,0 +1,31 @@
+package osinfo
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// Implemented in cpuid_amd64.s
+func cpuid(leaf, subleaf uint32) (eax, ebx, ecx, edx uint32)
+
+func readHypervisorCPUID() hypervisorCPUID {
+	_, _, ecx, _ := cpuid(1, 0)
+	result := hypervisorCPUID{available: true, present: ecx&(1<<31) != 0}
+	if !result.present {
+		return result
+	}
+
+	maxLeaf, ebx, ecx, edx := cpuid(0x40000000, 0)
+	signature := make([]byte, 12)
+	binary.LittleEndian.PutUint32(signature[0:], ebx)
+	binary.LittleEndian.PutUint32(signature[4:], ecx)
+	binary.LittleEndian.PutUint32(signature[8:], edx)
+	result.signature = strings.TrimRight(string(signature), "\x00")
+
+	// The CreatePartitions privilege is only granted to the root partition
+	if result.signature == "Microsoft Hv" && maxLeaf >= 0x40000003 {
+		_, ebx, _, _ = cpuid(0x40000003, 0)
+		result.rootPartition = ebx&1 != 0
+	}
+	return result
+}
@@ -0,0 +1,10 @@
+//go:build !amd64
+// +build !amd64
+
+package osinfo
+
+// CPUID is only checked on amd64. Other architectures fall back to the files
+// in /sys and /proc.
+func readHypervisorCPUID() hypervisorCPUID {
+	return hypervisorCPUID{}
+}
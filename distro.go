@@ -0,0 +1,107 @@
+package osinfo
+
+import "strings"
+
+// Distribution families, as reported in OSInfo.DistroFamily
+const (
+	DistroFamilyDebian = "debian"
+	// Red Hat Enterprise Linux, its clones and Fedora
+	DistroFamilyRHEL   = "rhel"
+	DistroFamilySUSE   = "suse"
+	DistroFamilyArch   = "arch"
+	DistroFamilyAlpine = "alpine"
+	DistroFamilyGentoo = "gentoo"
+)
+
+// Maps distribution IDs and ID_LIKE values to their family.
+// Derivatives that omit ID_LIKE from their os-release must be listed here.
+var distroFamilies = map[string]string{
+	"debian":     DistroFamilyDebian,
+	"ubuntu":     DistroFamilyDebian,
+	"raspbian":   DistroFamilyDebian,
+	"linuxmint":  DistroFamilyDebian,
+	"pop":        DistroFamilyDebian,
+	"elementary": DistroFamilyDebian,
+	"kali":       DistroFamilyDebian,
+	"devuan":     DistroFamilyDebian,
+	"zorin":      DistroFamilyDebian,
+	"neon":       DistroFamilyDebian,
+
+	"rhel":       DistroFamilyRHEL,
+	"fedora":     DistroFamilyRHEL,
+	"centos":     DistroFamilyRHEL,
+	"rocky":      DistroFamilyRHEL,
+	"almalinux":  DistroFamilyRHEL,
+	"ol":         DistroFamilyRHEL,
+	"amzn":       DistroFamilyRHEL,
+	"scientific": DistroFamilyRHEL,
+	"cloudlinux": DistroFamilyRHEL,
+	"eurolinux":  DistroFamilyRHEL,
+	"virtuozzo":  DistroFamilyRHEL,
+
+	"suse":                DistroFamilySUSE,
+	"opensuse":            DistroFamilySUSE,
+	"opensuse-leap":       DistroFamilySUSE,
+	"opensuse-tumbleweed": DistroFamilySUSE,
+	"sles":                DistroFamilySUSE,
+	"sles_sap":            DistroFamilySUSE,
+	"sled":                DistroFamilySUSE,
+
+	"arch":         DistroFamilyArch,
+	"archarm":      DistroFamilyArch,
+	"manjaro":      DistroFamilyArch,
+	"manjarolinux": DistroFamilyArch,
+	"endeavouros":  DistroFamilyArch,
+	"garuda":       DistroFamilyArch,
+	"artix":        DistroFamilyArch,
+
+	"alpine":       DistroFamilyAlpine,
+	"postmarketos": DistroFamilyAlpine,
+	"gentoo":       DistroFamilyGentoo,
+	"funtoo":       DistroFamilyGentoo,
+	"pentoo":       DistroFamilyGentoo,
+	"sabayon":      DistroFamilyGentoo,
+}
+
+// classifyDistroFamily returns the family of the distribution with the given
+// ID, falling back to each of its ID_LIKE values in order.
+// An empty string is returned when the family is unknown.
+func classifyDistroFamily(id string, idLike []string) string {
+	for _, candidate := range append([]string{id}, idLike...) {
+		if family, ok := distroFamilies[strings.ToLower(candidate)]; ok {
+			return family
+		}
+	}
+	return ""
+}
+
+// IsDebianLike returns true for Debian and its derivatives (Ubuntu, Mint...).
+func (info *OSInfo) IsDebianLike() bool {
+	return info.DistroFamily == DistroFamilyDebian
+}
+
+// IsRHELLike returns true for Red Hat Enterprise Linux, its clones (CentOS,
+// Rocky, AlmaLinux, Oracle Linux, Amazon Linux...) and Fedora.
+func (info *OSInfo) IsRHELLike() bool {
+	return info.DistroFamily == DistroFamilyRHEL
+}
+
+// IsSUSELike returns true for SLES, openSUSE and their derivatives.
+func (info *OSInfo) IsSUSELike() bool {
+	return info.DistroFamily == DistroFamilySUSE
+}
+
+// IsArchLike returns true for Arch Linux and its derivatives (Manjaro...).
+func (info *OSInfo) IsArchLike() bool {
+	return info.DistroFamily == DistroFamilyArch
+}
+
+// IsAlpineLike returns true for Alpine Linux and its derivatives.
+func (info *OSInfo) IsAlpineLike() bool {
+	return info.DistroFamily == DistroFamilyAlpine
+}
+
+// IsGentooLike returns true for Gentoo and its derivatives.
+func (info *OSInfo) IsGentooLike() bool {
+	return info.DistroFamily == DistroFamilyGentoo
+}
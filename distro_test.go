@@ -0,0 +1,80 @@
+package osinfo
+
+import "testing"
+
+func TestDistroFamilyFixtures(t *testing.T) {
+	fixtures := []struct {
+		osRelease  string
+		lsbRelease string
+		family     string
+	}{
+		{alpineOSRelease, "", DistroFamilyAlpine},
+		{centosOSRelease, "", DistroFamilyRHEL},
+		{debianOSRelease, "", DistroFamilyDebian},
+		{fedoraOSRelease, "", DistroFamilyRHEL},
+		{gentooOSRelease, "", DistroFamilyGentoo},
+		{kaliOSRelease, "", DistroFamilyDebian},
+		{manjaroOSRelease, manjaroLSBRelease, DistroFamilyArch},
+		{openSUSEOSRelease, "", DistroFamilySUSE},
+		{oracleOSRelease, "", DistroFamilyRHEL},
+		{ubuntuOSRelease, ubuntuLSBRelease, DistroFamilyDebian},
+	}
+
+	for _, fixture := range fixtures {
+		info := new(OSInfo)
+		parseEtcOSRelease(info, fixture.osRelease)
+		parseEtcLSBRelease(info, fixture.lsbRelease)
+		expectEqualStrings(t, fixture.family, classifyDistroFamily(info.ID, info.OSRelease.IDLike))
+	}
+}
+
+func TestDistroFamilyWithoutIDLike(t *testing.T) {
+	ids := []struct {
+		id     string
+		family string
+	}{
+		{"rocky", DistroFamilyRHEL},
+		{"almalinux", DistroFamilyRHEL},
+		{"ol", DistroFamilyRHEL},
+		{"amzn", DistroFamilyRHEL},
+		{"pop", DistroFamilyDebian},
+		{"linuxmint", DistroFamilyDebian},
+		{"Ubuntu", DistroFamilyDebian},
+		{"ManjaroLinux", DistroFamilyArch},
+		{"nixos", ""},
+		{"", ""},
+	}
+
+	for _, entry := range ids {
+		expectEqualStrings(t, entry.family, classifyDistroFamily(entry.id, nil))
+	}
+}
+
+func TestDistroFamilyFromIDLike(t *testing.T) {
+	expectEqualStrings(t, DistroFamilyDebian, classifyDistroFamily("some-derivative", []string{"ubuntu", "debian"}))
+	expectEqualStrings(t, DistroFamilySUSE, classifyDistroFamily("sle-micro", []string{"suse"}))
+	expectEqualStrings(t, "", classifyDistroFamily("unknown", []string{"also-unknown"}))
+}
+
+func TestDistroFamilyHelpers(t *testing.T) {
+	info := &OSInfo{DistroFamily: DistroFamilyRHEL}
+	if !info.IsRHELLike() {
+		t.Error("Expected IsRHELLike to be true")
+	}
+	if info.IsDebianLike() || info.IsSUSELike() || info.IsArchLike() || info.IsAlpineLike() || info.IsGentooLike() {
+		t.Error("Expected only IsRHELLike to be true")
+	}
+}
+
+func TestDistroFamilyFromFS(t *testing.T) {
+	info, err := GetOSInfoFromFS(legacyFS(map[string]string{
+		"etc/os-release": "ID=pop\nNAME=\"Pop!_OS\"\nVERSION_ID=\"22.04\"\n",
+	}))
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, DistroFamilyDebian, info.DistroFamily)
+	if !info.IsDebianLike() {
+		t.Error("Expected IsDebianLike to be true")
+	}
+}
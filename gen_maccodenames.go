@@ -0,0 +1,61 @@
+//go:build ignore
+// +build ignore
+
+// Generates maccodenames.go from maccodenames.txt.
+// Run with "go generate" from the package directory.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+func main() {
+	file, err := os.Open("maccodenames.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	lineRE := regexp.MustCompile(`^(\d+(?:\.\d+)?)\s+(\S.*)$`)
+	var entries bytes.Buffer
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		found := lineRE.FindStringSubmatch(line)
+		if len(found) == 0 {
+			log.Fatalf("maccodenames.txt:%v: Could not parse [%v]", lineNumber, line)
+		}
+		fmt.Fprintf(&entries, "\t%q: %q,\n", found[1], strings.TrimSpace(found[2]))
+	}
+	if err = scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	source := fmt.Sprintf(`// Code generated by gen_maccodenames.go from maccodenames.txt; DO NOT EDIT.
+
+package osinfo
+
+// Keyed on major.minor up to 10.x, and on the major version from 11 onwards.
+var macCodeNames = map[string]string{
+%v}
+`, entries.String())
+
+	formatted, err := format.Source([]byte(source))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile("maccodenames.go", formatted, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
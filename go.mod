@@ -1,3 +1,3 @@
 module github.com/blackfireio/osinfo
 
-go 1.11
+go 1.16
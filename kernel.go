@@ -0,0 +1,293 @@
+package osinfo
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kernel describes the running kernel.
+type Kernel struct {
+	// The kernel release, such as "5.15.0-91-generic" (uname -r)
+	Release string
+	// The kernel version string, such as
+	// "#101-Ubuntu SMP Tue Nov 14 13:30:08 UTC 2023" (uname -v)
+	Version string
+	// The kernel ABI version, such as "1400097" (uname -K, FreeBSD only)
+	Build string
+
+	// The kernel variant named in the release, such as KernelFlavorGeneric
+	// or KernelFlavorAWS. Empty if the release doesn't name one (Linux only)
+	Flavor string
+	// Built for multiple processors (Linux only)
+	SMP bool
+	// The preemption model the kernel was built with: "PREEMPT",
+	// "PREEMPT_DYNAMIC", "PREEMPT_RT", or empty if it isn't preemptible
+	// (Linux only)
+	Preempt string
+	// Running with the PREEMPT_RT real-time patches (Linux only)
+	PreemptRT bool
+	// The compiler the kernel was built with, such as
+	// "gcc-12 (Debian 12.2.0-14) 12.2.0" (Linux only)
+	Compiler string
+}
+
+// ParsedRelease parses Release into a comparable KernelVersion.
+func (k Kernel) ParsedRelease() (KernelVersion, error) {
+	return ParseKernelVersion(k.Release)
+}
+
+const (
+	KernelFlavorGeneric    = "generic"
+	KernelFlavorLowLatency = "lowlatency"
+	KernelFlavorRealtime   = "rt"
+	KernelFlavorAWS        = "aws"
+	KernelFlavorAzure      = "azure"
+	KernelFlavorGCP        = "gcp"
+	// Ubuntu's Oracle Cloud kernel and Oracle's Unbreakable Enterprise Kernel
+	KernelFlavorOracle = "oracle"
+	KernelFlavorCloud  = "cloud"
+	KernelFlavorGrsec  = "grsec"
+	// WSL 1 emulates Linux on the Windows kernel, which reports itself as
+	// "4.4.0-19041-Microsoft"
+	KernelFlavorWSL1 = "wsl1"
+	KernelFlavorWSL2 = "wsl2"
+)
+
+// Words in kernel releases naming a flavor, in order of precedence.
+var kernelFlavorWords = []struct {
+	word   string
+	flavor string
+}{
+	{"wsl2", KernelFlavorWSL2},
+	{"microsoft", KernelFlavorWSL1},
+	{"grsec", KernelFlavorGrsec},
+	{"rt", KernelFlavorRealtime},
+	{"realtime", KernelFlavorRealtime},
+	{"lowlatency", KernelFlavorLowLatency},
+	{"aws", KernelFlavorAWS},
+	{"amzn", KernelFlavorAWS},
+	{"azure", KernelFlavorAzure},
+	{"gcp", KernelFlavorGCP},
+	{"gke", KernelFlavorGCP},
+	{"oracle", KernelFlavorOracle},
+	{"uek", KernelFlavorOracle},
+	{"cloud", KernelFlavorCloud},
+	{"generic", KernelFlavorGeneric},
+}
+
+// Some flavors are glued to a number, as in RHEL's real-time
+// "4.18.0-477.10.1.rt7.273.el8_8.x86_64", Amazon Linux's
+// "5.10.205-195.807.amzn2.x86_64" or Oracle's "5.15.0-200.131.27.el8uek.x86_64".
+var kernelFlavorWordRE = regexp.MustCompile(`^(rt|amzn)\d+$|^el\d+(uek)$`)
+
+// classifyKernelFlavor finds the flavor named in a kernel release.
+func classifyKernelFlavor(release string) string {
+	// WSL 2 kernels are "5.15.153.1-microsoft-standard-WSL2", or just
+	// "4.19.128-microsoft-standard" before 5.10
+	if strings.Contains(strings.ToLower(release), "microsoft-standard") {
+		return KernelFlavorWSL2
+	}
+	words := strings.FieldsFunc(strings.ToLower(release), func(r rune) bool {
+		return strings.ContainsRune("-._+~", r)
+	})
+	for i, word := range words {
+		if found := kernelFlavorWordRE.FindStringSubmatch(word); found != nil {
+			words[i] = found[1] + found[2]
+		}
+	}
+	for _, candidate := range kernelFlavorWords {
+		if containsString(words, candidate.word) {
+			return candidate.flavor
+		}
+	}
+	return ""
+}
+
+// KernelVersion is a parsed, comparable kernel release.
+type KernelVersion struct {
+	Major int
+	Minor int
+	Patch int
+	// The distribution's build of the upstream version, such as 91 in Ubuntu's
+	// "5.15.0-91-generic" or 477 in RHEL's "4.18.0-477.el8.x86_64"
+	Revision int
+	// Whatever follows, such as "generic", "el8.x86_64" or "RELEASE-p6"
+	Suffix string
+	// The string the version was parsed from
+	Original string
+}
+
+var kernelVersionRE = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?(?:[-.](\d+)\b)?(?:[-.+_~](.*))?$`)
+
+// ParseKernelVersion parses a kernel release such as "6.1.0-18-amd64".
+func ParseKernelVersion(release string) (v KernelVersion, err error) {
+	v.Original = release
+	found := kernelVersionRE.FindStringSubmatch(strings.TrimSpace(release))
+	if len(found) == 0 {
+		return v, fmt.Errorf("%v: Not a kernel version", release)
+	}
+
+	components := []*int{&v.Major, &v.Minor, &v.Patch, &v.Revision}
+	for i, component := range found[1:5] {
+		if component == "" {
+			continue
+		}
+		if *components[i], err = strconv.Atoi(component); err != nil {
+			return v, fmt.Errorf("%v: %v", release, err)
+		}
+	}
+	v.Suffix = found[5]
+	return
+}
+
+// Compare returns -1, 0 or 1 when v is lower than, equal to or greater than
+// other. Suffixes are only compared when everything else is equal, the same
+// way as for Version: release candidates such as "6.10.0-rc2" come before
+// the release, and numbers are compared numerically so that "el8_10" is after
+// "el8_9".
+func (v KernelVersion) Compare(other KernelVersion) int {
+	for _, pair := range [][2]int{
+		{v.Major, other.Major},
+		{v.Minor, other.Minor},
+		{v.Patch, other.Patch},
+		{v.Revision, other.Revision},
+	} {
+		if result := compareInts(pair[0], pair[1]); result != 0 {
+			return result
+		}
+	}
+	return compareVersionSuffixes(v.Suffix, other.Suffix)
+}
+
+// AtLeast returns true if the upstream version of v is greater than or equal
+// to other, ignoring revisions and suffixes. For example, "5.15.0-91-generic"
+// is at least "5.15".
+func (v KernelVersion) AtLeast(other KernelVersion) bool {
+	other.Revision = v.Revision
+	other.Suffix = v.Suffix
+	return v.Compare(other) >= 0
+}
+
+func (v KernelVersion) String() string {
+	return v.Original
+}
+
+// readLinuxKernel reads the running kernel's details from procfs, which
+// has the same values as uname(2).
+func readLinuxKernel(fsys fs.FS) (kernel Kernel, err error) {
+	if kernel.Release, err = readTextFile(fsys, "proc/sys/kernel/osrelease"); err != nil {
+		return
+	}
+	if kernel.Version, err = readTextFile(fsys, "proc/sys/kernel/version"); err != nil {
+		return
+	}
+	kernel.Release = strings.TrimSpace(kernel.Release)
+	kernel.Version = strings.TrimSpace(kernel.Version)
+	return
+}
+
+// parseKernelBuildFlags reads the SMP and PREEMPT flags from the kernel
+// version string, such as "#1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1 (2024-02-01)".
+func parseKernelBuildFlags(kernel *Kernel) {
+	words := strings.Fields(kernel.Version)
+	for i, word := range words {
+		switch word {
+		case "SMP":
+			kernel.SMP = true
+		case "PREEMPT_RT", "PREEMPT_DYNAMIC":
+			kernel.Preempt = word
+		case "PREEMPT":
+			// Older real-time kernels have "PREEMPT RT"
+			if i+1 < len(words) && words[i+1] == "RT" {
+				kernel.Preempt = "PREEMPT_RT"
+			} else {
+				kernel.Preempt = word
+			}
+		}
+	}
+	if kernel.Preempt == "PREEMPT_RT" {
+		kernel.PreemptRT = true
+	}
+}
+
+// parseKernelCompiler extracts the compiler from /proc/version, which has
+// the builder and the toolchain in parentheses before the version string:
+//
+//	Linux version 6.1.0-18-amd64 (debian-kernel@lists.debian.org) (gcc-12 (Debian 12.2.0-14) 12.2.0, GNU ld (GNU Binutils for Debian) 2.40) #1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1 (2024-02-01)
+func parseKernelCompiler(procVersion string) string {
+	var groups []string
+	depth, start := 0, 0
+scan:
+	for i, c := range procVersion {
+		switch c {
+		case '#':
+			if depth == 0 {
+				break scan
+			}
+		case '(':
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					groups = append(groups, procVersion[start:i])
+				}
+			}
+		}
+	}
+	if len(groups) < 2 {
+		return ""
+	}
+
+	// The linker follows the compiler, such as ", GNU ld ..." or ", LLD 17.0.2"
+	toolchain := groups[1]
+	depth = 0
+	for i, c := range toolchain {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				return strings.TrimSpace(toolchain[:i])
+			}
+		}
+	}
+	return strings.TrimSpace(toolchain)
+}
+
+// enrichLinuxKernel fills in what can be derived from the release and the
+// version string, and from /proc/version and /sys/kernel/realtime.
+func enrichLinuxKernel(fsys fs.FS, kernel *Kernel) {
+	kernel.Flavor = classifyKernelFlavor(kernel.Release)
+	parseKernelBuildFlags(kernel)
+	if procVersion, err := readTextFile(fsys, "proc/version"); err == nil {
+		kernel.Compiler = parseKernelCompiler(procVersion)
+	}
+	// Only present on real-time kernels
+	if realtime, err := readTextFile(fsys, "sys/kernel/realtime"); err == nil && strings.TrimSpace(realtime) == "1" {
+		kernel.PreemptRT = true
+	}
+}
+
+// readUnameKernel reads the running kernel's details with uname, which is
+// how BSDs and macOS expose them. uname -K is only supported by FreeBSD.
+func readUnameKernel(withBuild bool) (kernel Kernel, err error) {
+	if kernel.Release, err = readCommandOutput("/usr/bin/uname", "-r"); err != nil {
+		return
+	}
+	if kernel.Version, err = readCommandOutput("/usr/bin/uname", "-v"); err != nil {
+		return
+	}
+	if withBuild {
+		kernel.Build, err = readCommandOutput("/usr/bin/uname", "-K")
+	}
+	return
+}
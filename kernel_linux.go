@@ -0,0 +1,30 @@
+package osinfo
+
+import "syscall"
+
+// unameKernel reads the running kernel's details with uname(2), for when
+// /proc isn't mounted.
+func unameKernel() (kernel Kernel, err error) {
+	var uts syscall.Utsname
+	if err = syscall.Uname(&uts); err != nil {
+		return
+	}
+	// The fields are int8 or uint8 depending on the architecture
+	release := make([]byte, 0, len(uts.Release))
+	for _, c := range uts.Release {
+		if c == 0 {
+			break
+		}
+		release = append(release, byte(c))
+	}
+	version := make([]byte, 0, len(uts.Version))
+	for _, c := range uts.Version {
+		if c == 0 {
+			break
+		}
+		version = append(version, byte(c))
+	}
+	kernel.Release = string(release)
+	kernel.Version = string(version)
+	return
+}
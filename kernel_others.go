@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package osinfo
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func unameKernel() (kernel Kernel, err error) {
+	return kernel, fmt.Errorf("%v: uname(2) is only used on Linux", runtime.GOOS)
+}
@@ -0,0 +1,250 @@
+package osinfo
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func expectKernelVersion(t *testing.T, release string, major, minor, patch, revision int, suffix string) {
+	v, err := ParseKernelVersion(release)
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualInts(t, major, v.Major)
+	expectEqualInts(t, minor, v.Minor)
+	expectEqualInts(t, patch, v.Patch)
+	expectEqualInts(t, revision, v.Revision)
+	expectEqualStrings(t, suffix, v.Suffix)
+	expectEqualStrings(t, release, v.String())
+}
+
+func expectKernelCompare(t *testing.T, expected int, a, b string) {
+	va, err := ParseKernelVersion(a)
+	if err != nil {
+		t.Error(err)
+	}
+	vb, err := ParseKernelVersion(b)
+	if err != nil {
+		t.Error(err)
+	}
+	if actual := va.Compare(vb); actual != expected {
+		t.Errorf("Expected %v compared to %v to be %v but got %v", a, b, expected, actual)
+	}
+}
+
+func TestParseKernelVersion(t *testing.T) {
+	// Ubuntu
+	expectKernelVersion(t, "5.15.0-91-generic", 5, 15, 0, 91, "generic")
+	expectKernelVersion(t, "6.8.0-1008-aws", 6, 8, 0, 1008, "aws")
+	// RHEL and derivatives
+	expectKernelVersion(t, "4.18.0-477.el8.x86_64", 4, 18, 0, 477, "el8.x86_64")
+	expectKernelVersion(t, "4.18.0-477.27.1.el8_8.x86_64", 4, 18, 0, 477, "27.1.el8_8.x86_64")
+	expectKernelVersion(t, "3.10.0-1160.el7.x86_64", 3, 10, 0, 1160, "el7.x86_64")
+	expectKernelVersion(t, "6.8.9-300.fc40.aarch64", 6, 8, 9, 300, "fc40.aarch64")
+	// Debian
+	expectKernelVersion(t, "6.1.0-18-amd64", 6, 1, 0, 18, "amd64")
+	expectKernelVersion(t, "6.6.31+rpt-rpi-v8", 6, 6, 31, 0, "rpt-rpi-v8")
+	// Others
+	expectKernelVersion(t, "6.9.3-arch1-1", 6, 9, 3, 0, "arch1-1")
+	expectKernelVersion(t, "6.6.32-0-lts", 6, 6, 32, 0, "lts")
+	expectKernelVersion(t, "5.15.153.1-microsoft-standard-WSL2", 5, 15, 153, 1, "microsoft-standard-WSL2")
+	expectKernelVersion(t, "6.10.0-rc2", 6, 10, 0, 0, "rc2")
+	expectKernelVersion(t, "6.9.0", 6, 9, 0, 0, "")
+	// FreeBSD and macOS
+	expectKernelVersion(t, "14.0-RELEASE-p6", 14, 0, 0, 0, "RELEASE-p6")
+	expectKernelVersion(t, "23.5.0", 23, 5, 0, 0, "")
+}
+
+func TestParseKernelVersionErrors(t *testing.T) {
+	for _, release := range []string{"", "6", "generic", "6.x"} {
+		if _, err := ParseKernelVersion(release); err == nil {
+			t.Errorf("Expected an error parsing [%v]", release)
+		}
+	}
+}
+
+func TestKernelVersionCompare(t *testing.T) {
+	expectKernelCompare(t, 0, "5.15.0-91-generic", "5.15.0-91-generic")
+	expectKernelCompare(t, 1, "5.15.0-91-generic", "5.15.0-88-generic")
+	expectKernelCompare(t, -1, "5.15.0-91-generic", "6.1.0-18-amd64")
+	expectKernelCompare(t, 1, "5.15.0-101-generic", "5.15.0-91-generic")
+	expectKernelCompare(t, 1, "4.18.0-477.27.1.el8_8.x86_64", "4.18.0-477.10.1.el8_8.x86_64")
+	expectKernelCompare(t, 1, "4.18.0-553.el8_10.x86_64", "4.18.0-477.el8_9.x86_64")
+	expectKernelCompare(t, 1, "4.18.0-477.el8_10.x86_64", "4.18.0-477.el8_9.x86_64")
+	expectKernelCompare(t, 1, "6.10.0", "6.9.12")
+	expectKernelCompare(t, 1, "14.0-RELEASE-p6", "14.0-RELEASE-p5")
+	expectKernelCompare(t, 1, "14.0-RELEASE-p10", "14.0-RELEASE-p9")
+	// Release candidates come before the release
+	expectKernelCompare(t, -1, "6.10.0-rc2", "6.10.0")
+	expectKernelCompare(t, -1, "6.10.0-rc2", "6.10.0-rc10")
+	expectKernelCompare(t, 1, "6.10.0-rc7", "6.9.12")
+	expectKernelCompare(t, 0, "14.0-RELEASE", "14.0")
+}
+
+func TestKernelAndOSVersionsAgree(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"6.10.0-rc2", "6.10.0"},
+		{"14.0-RELEASE-p10", "14.0-RELEASE-p9"},
+		{"14.0-BETA3", "14.0-RC1"},
+		{"14.0-RELEASE", "14.0"},
+	} {
+		kernelA, _ := ParseKernelVersion(pair[0])
+		kernelB, _ := ParseKernelVersion(pair[1])
+		versionA, _ := ParseVersion(pair[0])
+		versionB, _ := ParseVersion(pair[1])
+		if kernelA.Compare(kernelB) != versionA.Compare(versionB) {
+			t.Errorf("Kernel and OS versions disagree comparing %v and %v", pair[0], pair[1])
+		}
+	}
+}
+
+func TestKernelVersionAtLeast(t *testing.T) {
+	v, _ := ParseKernelVersion("5.15.0-91-generic")
+	for _, other := range []string{"5.15", "5.15.0", "5.15.0-100-generic", "5.8", "4.19.300"} {
+		o, _ := ParseKernelVersion(other)
+		if !v.AtLeast(o) {
+			t.Errorf("Expected %v to be at least %v", v, other)
+		}
+	}
+	for _, other := range []string{"5.16", "5.15.1", "6.1.0-18-amd64"} {
+		o, _ := ParseKernelVersion(other)
+		if v.AtLeast(o) {
+			t.Errorf("Expected %v not to be at least %v", v, other)
+		}
+	}
+}
+
+func TestReadLinuxKernel(t *testing.T) {
+	kernel, err := readLinuxKernel(legacyFS(map[string]string{
+		"proc/sys/kernel/osrelease": "6.1.0-18-amd64\n",
+		"proc/sys/kernel/version":   "#1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1 (2024-02-01)\n",
+	}))
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "6.1.0-18-amd64", kernel.Release)
+	expectEqualStrings(t, "#1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1 (2024-02-01)", kernel.Version)
+	expectEqualStrings(t, "", kernel.Build)
+
+	v, err := kernel.ParsedRelease()
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualInts(t, 18, v.Revision)
+
+	if _, err := readLinuxKernel(fstest.MapFS{}); err == nil {
+		t.Errorf("Expected an error without procfs")
+	}
+}
+
+func TestClassifyKernelFlavor(t *testing.T) {
+	for release, flavor := range map[string]string{
+		"5.15.0-91-generic":                    KernelFlavorGeneric,
+		"5.15.0-91-lowlatency":                 KernelFlavorLowLatency,
+		"6.8.1-1004-realtime":                  KernelFlavorRealtime,
+		"6.1.0-18-rt-amd64":                    KernelFlavorRealtime,
+		"4.18.0-477.10.1.rt7.273.el8_8.x86_64": KernelFlavorRealtime,
+		"5.14.21-150500.13.5-rt":               KernelFlavorRealtime,
+		"6.5.0-1014-aws":                       KernelFlavorAWS,
+		"5.10.205-195.807.amzn2.x86_64":        KernelFlavorAWS,
+		"6.5.0-1016-azure":                     KernelFlavorAzure,
+		"6.5.0-1013-gcp":                       KernelFlavorGCP,
+		"5.15.0-1049-oracle":                   KernelFlavorOracle,
+		"5.15.0-200.131.27.el8uek.x86_64":      KernelFlavorOracle,
+		"6.1.0-18-cloud-amd64":                 KernelFlavorCloud,
+		"4.9.74-grsec":                         KernelFlavorGrsec,
+		"5.15.153.1-microsoft-standard-WSL2":   KernelFlavorWSL2,
+		"6.1.0-18-amd64":                       "",
+		"4.18.0-477.el8.x86_64":                "",
+		"6.9.3-arch1-1":                        "",
+		"5.14.21-150500.55.39-default":         "",
+		"6.6.31+rpt-rpi-v8":                    "",
+		"6.8.0-31-generic-64k":                 KernelFlavorGeneric,
+		"3.10.0-1160.102.1.el7.x86_64":         "",
+		"4.4.0-19041-Microsoft":                KernelFlavorWSL1,
+		"4.19.128-microsoft-standard":          KernelFlavorWSL2,
+		"6.6.8-200.fc39.x86_64+debug":          "",
+		"5.4.0-1110-gke":                       KernelFlavorGCP,
+		"6.10.0-rc2":                           "",
+		"4.14.336-253.554.amzn2.aarch64":       KernelFlavorAWS,
+		"5.15.0-1057-realtime":                 KernelFlavorRealtime,
+		"4.18.0-513.5.1.rt7.307.el8_9.x86_64":  KernelFlavorRealtime,
+		"5.15.0-100.96.32.el8uek.x86_64":       KernelFlavorOracle,
+		"6.1.0-0.deb11.17-cloud-arm64":         KernelFlavorCloud,
+		"6.1.0-18-rt-arm64":                    KernelFlavorRealtime,
+		"5.15.146.1-microsoft-standard-WSL2+":  KernelFlavorWSL2,
+		"4.19.0-26-amd64":                      "",
+		"5.15.0-1053-azure-fde":                KernelFlavorAzure,
+		"6.2.0-1018-lowlatency-hwe-22.04":      KernelFlavorLowLatency,
+		"6.5.0-28-generic-hwe-22.04":           KernelFlavorGeneric,
+	} {
+		if actual := classifyKernelFlavor(release); actual != flavor {
+			t.Errorf("Expected flavor [%v] for %v but got [%v]", flavor, release, actual)
+		}
+	}
+}
+
+func expectKernelBuildFlags(t *testing.T, version string, smp bool, preempt string, preemptRT bool) {
+	kernel := Kernel{Version: version}
+	parseKernelBuildFlags(&kernel)
+	if kernel.SMP != smp {
+		t.Errorf("Expected SMP %v for [%v]", smp, version)
+	}
+	expectEqualStrings(t, preempt, kernel.Preempt)
+	if kernel.PreemptRT != preemptRT {
+		t.Errorf("Expected PreemptRT %v for [%v]", preemptRT, version)
+	}
+}
+
+func TestKernelBuildFlags(t *testing.T) {
+	expectKernelBuildFlags(t, "#101-Ubuntu SMP Tue Nov 14 13:30:08 UTC 2023", true, "", false)
+	expectKernelBuildFlags(t, "#1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1 (2024-02-01)", true, "PREEMPT_DYNAMIC", false)
+	expectKernelBuildFlags(t, "#1 SMP PREEMPT_RT Debian 6.1.76-1 (2024-02-01)", true, "PREEMPT_RT", true)
+	expectKernelBuildFlags(t, "#1 SMP PREEMPT RT Thu Oct 26 12:11:14 UTC 2023", true, "PREEMPT_RT", true)
+	expectKernelBuildFlags(t, "#1 SMP PREEMPT Mon Jun  3 12:00:00 UTC 2024", true, "PREEMPT", false)
+	expectKernelBuildFlags(t, "#1 PREEMPT Wed Feb 14 12:00:00 UTC 2024", false, "PREEMPT", false)
+	expectKernelBuildFlags(t, "#1 Wed Feb 14 12:00:00 UTC 2024", false, "", false)
+}
+
+func TestKernelCompiler(t *testing.T) {
+	for procVersion, compiler := range map[string]string{
+		"Linux version 6.1.0-18-amd64 (debian-kernel@lists.debian.org) (gcc-12 (Debian 12.2.0-14) 12.2.0, GNU ld (GNU Binutils for Debian) 2.40) #1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1 (2024-02-01)\n":                                                   "gcc-12 (Debian 12.2.0-14) 12.2.0",
+		"Linux version 5.15.0-91-generic (buildd@lcy02-amd64-045) (gcc (Ubuntu 11.4.0-1ubuntu1~22.04) 11.4.0, GNU ld (GNU Binutils for Ubuntu) 2.38) #101-Ubuntu SMP Tue Nov 14 13:30:08 UTC 2023":                                                        "gcc (Ubuntu 11.4.0-1ubuntu1~22.04) 11.4.0",
+		"Linux version 3.10.0-1160.el7.x86_64 (mockbuild@kbuilder.bsys.centos.org) (gcc version 4.8.5 20150623 (Red Hat 4.8.5-44) (GCC) ) #1 SMP Wed Nov 4 12:05:39 UTC 2020":                                                                             "gcc version 4.8.5 20150623 (Red Hat 4.8.5-44) (GCC)",
+		"Linux version 6.6.32-0-lts (buildozer@build-3-20-x86_64) (gcc (Alpine 13.2.1_git20240309) 13.2.1 20240309, GNU ld (GNU Binutils) 2.42) #1-Alpine SMP PREEMPT_DYNAMIC Fri, 24 May 2024 10:11:26 +0000":                                            "gcc (Alpine 13.2.1_git20240309) 13.2.1 20240309",
+		"Linux version 5.10.198-android13-4 (kleaf@build-host) (Android (8508608, based on r450784e) clang version 14.0.7 (https://android.googlesource.com/toolchain/llvm-project 4c603efb0cca), LLD 14.0.7) #1 SMP PREEMPT Mon Jun 3 00:00:00 UTC 2024": "Android (8508608, based on r450784e) clang version 14.0.7 (https://android.googlesource.com/toolchain/llvm-project 4c603efb0cca)",
+		"Linux version 6.1.0 (root@localhost) #1 SMP (something)": "",
+		"": "",
+	} {
+		expectEqualStrings(t, compiler, parseKernelCompiler(procVersion))
+	}
+}
+
+func TestEnrichLinuxKernel(t *testing.T) {
+	fsys := legacyFS(map[string]string{
+		"proc/sys/kernel/osrelease": "6.1.0-18-rt-amd64\n",
+		"proc/sys/kernel/version":   "#1 SMP PREEMPT_RT Debian 6.1.76-1 (2024-02-01)\n",
+		"proc/version":              "Linux version 6.1.0-18-rt-amd64 (debian-kernel@lists.debian.org) (gcc-12 (Debian 12.2.0-14) 12.2.0, GNU ld (GNU Binutils for Debian) 2.40) #1 SMP PREEMPT_RT Debian 6.1.76-1 (2024-02-01)\n",
+		"sys/kernel/realtime":       "1\n",
+	})
+	kernel, err := readLinuxKernel(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enrichLinuxKernel(fsys, &kernel)
+	expectEqualStrings(t, KernelFlavorRealtime, kernel.Flavor)
+	expectEqualStrings(t, "PREEMPT_RT", kernel.Preempt)
+	expectEqualStrings(t, "gcc-12 (Debian 12.2.0-14) 12.2.0", kernel.Compiler)
+	if !kernel.SMP || !kernel.PreemptRT {
+		t.Errorf("Expected an SMP PREEMPT_RT kernel")
+	}
+
+	// An RT kernel whose version string doesn't say so
+	kernel = Kernel{Release: "4.18.0-477.10.1.rt7.273.el8_8.x86_64", Version: "#1 SMP Wed Apr 5 12:00:00 EDT 2023"}
+	enrichLinuxKernel(legacyFS(map[string]string{"sys/kernel/realtime": "1"}), &kernel)
+	expectEqualStrings(t, "", kernel.Preempt)
+	if !kernel.PreemptRT {
+		t.Errorf("Expected /sys/kernel/realtime to mark the kernel as PREEMPT_RT")
+	}
+	expectEqualStrings(t, "", kernel.Compiler)
+}
@@ -0,0 +1,176 @@
+package osinfo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// KernelConfig is the build configuration of a Linux kernel (its .config).
+type KernelConfig struct {
+	// The file the configuration was read from, such as "/proc/config.gz"
+	Source string
+	// Option names (including the CONFIG_ prefix) to values. Options that are
+	// "not set" have the value "n", and string values are unquoted.
+	Options map[string]string
+}
+
+// GetKernelConfig reads the configuration of the running kernel.
+func GetKernelConfig() (*KernelConfig, error) {
+	kernel, err := readLinuxKernel(os.DirFS("/"))
+	if err != nil {
+		if kernel, err = unameKernel(); err != nil {
+			return nil, err
+		}
+	}
+	return GetKernelConfigFromFS(os.DirFS("/"), kernel.Release)
+}
+
+// GetKernelConfigFromFS reads the configuration of the kernel with the given
+// release on the system whose root filesystem is fsys. It is read from
+// /boot/config-RELEASE or /lib/modules/RELEASE/config, or else from
+// /proc/config.gz when release is the kernel running on fsys (as named by
+// /proc/sys/kernel/osrelease). If release is empty, /proc/config.gz is read
+// first, and then the configuration of the newest kernel in /boot.
+func GetKernelConfigFromFS(fsys fs.FS, release string) (*KernelConfig, error) {
+	var candidates []string
+	// /proc/config.gz is the running kernel's, which may not be the one asked
+	// for, such as after an upgrade that hasn't been rebooted into yet
+	running := release == "" || release == readSysFile(fsys, "proc/sys/kernel/osrelease")
+	if release == "" {
+		candidates = append(candidates, "proc/config.gz")
+		release = newestBootKernel(fsys)
+	}
+	if release != "" {
+		candidates = append(candidates,
+			"boot/config-"+release,
+			"lib/modules/"+release+"/config",
+			"usr/lib/modules/"+release+"/config")
+	}
+	if running && !containsString(candidates, "proc/config.gz") {
+		candidates = append(candidates, "proc/config.gz")
+	}
+
+	for _, candidate := range candidates {
+		data, err := fs.ReadFile(fsys, candidate)
+		if err != nil {
+			continue
+		}
+		config, err := parseKernelConfig(data)
+		config.Source = "/" + candidate
+		if err != nil {
+			err = fmt.Errorf("%v: %v", config.Source, err)
+		}
+		return config, err
+	}
+	return nil, fmt.Errorf("Error: No kernel configuration found for kernel [%v]", release)
+}
+
+// newestBootKernel returns the newest kernel release with a configuration
+// in /boot, or an empty string if there is none.
+func newestBootKernel(fsys fs.FS) string {
+	matches, _ := fs.Glob(fsys, "boot/config-*")
+	newest := ""
+	var newestVersion KernelVersion
+	for _, match := range matches {
+		release := strings.TrimPrefix(path.Base(match), "config-")
+		version, err := ParseKernelVersion(release)
+		if err != nil {
+			continue
+		}
+		if newest == "" || version.Compare(newestVersion) > 0 {
+			newest = release
+			newestVersion = version
+		}
+	}
+	return newest
+}
+
+// parseKernelConfig parses a kernel configuration, which may be gzipped:
+//
+//	CONFIG_BPF_SYSCALL=y
+//	CONFIG_HZ=250
+//	# CONFIG_PREEMPT_RT is not set
+//
+// On error, the options that could be parsed are still returned.
+func parseKernelConfig(data []byte) (config *KernelConfig, err error) {
+	config = &KernelConfig{Options: make(map[string]string)}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		var reader *gzip.Reader
+		if reader, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+			return
+		}
+		if data, err = ioutil.ReadAll(reader); err != nil {
+			return
+		}
+	}
+
+	var problems []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "# CONFIG_") && strings.HasSuffix(line, " is not set") {
+			config.Options[strings.TrimSuffix(line[2:], " is not set")] = "n"
+			continue
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		separator := strings.IndexByte(line, '=')
+		if separator < 0 || !strings.HasPrefix(line, "CONFIG_") {
+			problems = append(problems, fmt.Sprintf("line %v: not a kernel option [%v]", i+1, line))
+			continue
+		}
+		value := line[separator+1:]
+		if strings.HasPrefix(value, `"`) {
+			var unquoteErr error
+			if value, unquoteErr = strconv.Unquote(value); unquoteErr != nil {
+				problems = append(problems, fmt.Sprintf("line %v: invalid string [%v]", i+1, line[separator+1:]))
+				continue
+			}
+		}
+		config.Options[line[:separator]] = value
+	}
+
+	if len(problems) > 0 {
+		err = fmt.Errorf("%v", strings.Join(problems, "; "))
+	}
+	return
+}
+
+// Option names may be given with or without the CONFIG_ prefix.
+func kernelOptionName(name string) string {
+	if strings.HasPrefix(name, "CONFIG_") {
+		return name
+	}
+	return "CONFIG_" + name
+}
+
+// Value returns the value of an option, such as "250" for CONFIG_HZ, "y" or
+// "m" for enabled options, "n" for options that are not set, or an empty
+// string for options that the configuration doesn't mention.
+func (c *KernelConfig) Value(name string) string {
+	return c.Options[kernelOptionName(name)]
+}
+
+// IsEnabled returns true if an option is built in or built as a module.
+func (c *KernelConfig) IsEnabled(name string) bool {
+	value := c.Value(name)
+	return value == "y" || value == "m"
+}
+
+// IsBuiltin returns true if an option is built into the kernel.
+func (c *KernelConfig) IsBuiltin(name string) bool {
+	return c.Value(name) == "y"
+}
+
+// IsModule returns true if an option is built as a loadable module.
+func (c *KernelConfig) IsModule(name string) bool {
+	return c.Value(name) == "m"
+}
@@ -0,0 +1,164 @@
+package osinfo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"testing/fstest"
+)
+
+func gzipFixture(t *testing.T, name string) []byte {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(readFixture(t, name)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return compressed.Bytes()
+}
+
+func expectDebianKernelConfig(t *testing.T, config *KernelConfig) {
+	expectEqualStrings(t, "250", config.Value("CONFIG_HZ"))
+	expectEqualStrings(t, "250", config.Value("HZ"))
+	expectEqualStrings(t, "gcc-12 (Debian 12.2.0-14) 12.2.0", config.Value("CONFIG_CC_VERSION_TEXT"))
+	expectEqualStrings(t, "(none)", config.Value("CONFIG_DEFAULT_HOSTNAME"))
+	expectEqualStrings(t, "", config.Value("CONFIG_LOCALVERSION"))
+	expectEqualStrings(t, "n", config.Value("CONFIG_PREEMPT"))
+	expectEqualStrings(t, "", config.Value("CONFIG_PREEMPT_RT"))
+
+	for _, option := range []string{"CONFIG_BPF_SYSCALL", "CONFIG_BPF_JIT", "CONFIG_DEBUG_INFO_BTF", "PERF_EVENTS", "CONFIG_X86_MSR"} {
+		if !config.IsEnabled(option) {
+			t.Errorf("Expected %v to be enabled", option)
+		}
+	}
+	for _, option := range []string{"CONFIG_BPF_PRELOAD", "CONFIG_PREEMPT_RT", "CONFIG_HZ_1000", "CONFIG_HZ"} {
+		if config.IsEnabled(option) {
+			t.Errorf("Expected %v not to be enabled", option)
+		}
+	}
+	if !config.IsBuiltin("CONFIG_BPF_SYSCALL") || config.IsModule("CONFIG_BPF_SYSCALL") {
+		t.Errorf("Expected CONFIG_BPF_SYSCALL to be built in")
+	}
+	if !config.IsModule("CONFIG_IKCONFIG") || config.IsBuiltin("CONFIG_IKCONFIG") {
+		t.Errorf("Expected CONFIG_IKCONFIG to be a module")
+	}
+}
+
+func TestKernelConfigFromBoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		"boot/config-6.1.0-18-amd64": {Data: readFixture(t, "config-6.1.0-18-amd64")},
+		"boot/config-6.1.0-17-amd64": {Data: []byte("CONFIG_HZ=100\n")},
+	}
+	config, err := GetKernelConfigFromFS(fsys, "6.1.0-18-amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "/boot/config-6.1.0-18-amd64", config.Source)
+	expectDebianKernelConfig(t, config)
+
+	config, err = GetKernelConfigFromFS(fsys, "6.1.0-17-amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "100", config.Value("CONFIG_HZ"))
+}
+
+func TestKernelConfigNewestInBoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		"boot/config-6.1.0-9-amd64":   {Data: []byte("CONFIG_HZ=100\n")},
+		"boot/config-6.1.0-18-amd64":  {Data: readFixture(t, "config-6.1.0-18-amd64")},
+		"boot/config-5.10.0-28-amd64": {Data: []byte("CONFIG_HZ=300\n")},
+	}
+	config, err := GetKernelConfigFromFS(fsys, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "/boot/config-6.1.0-18-amd64", config.Source)
+}
+
+func TestKernelConfigFromProc(t *testing.T) {
+	fsys := fstest.MapFS{
+		"proc/config.gz":            {Data: gzipFixture(t, "config-6.1.0-18-amd64")},
+		"proc/sys/kernel/osrelease": {Data: []byte("6.1.0-18-amd64\n")},
+	}
+	config, err := GetKernelConfigFromFS(fsys, "6.1.0-18-amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "/proc/config.gz", config.Source)
+	expectDebianKernelConfig(t, config)
+
+	config, err = GetKernelConfigFromFS(fsys, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "/proc/config.gz", config.Source)
+
+	// After an upgrade, /proc/config.gz is the old kernel's
+	if _, err := GetKernelConfigFromFS(fsys, "6.1.0-21-amd64"); err == nil {
+		t.Errorf("Expected an error for a kernel other than the running one")
+	}
+
+	// /boot has the configuration of the kernel that was asked for
+	fsys["boot/config-6.1.0-18-amd64"] = &fstest.MapFile{Data: []byte("CONFIG_HZ=100\n")}
+	fsys["boot/config-6.1.0-17-amd64"] = &fstest.MapFile{Data: []byte("CONFIG_HZ=300\n")}
+	config, err = GetKernelConfigFromFS(fsys, "6.1.0-18-amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "/boot/config-6.1.0-18-amd64", config.Source)
+	config, err = GetKernelConfigFromFS(fsys, "6.1.0-17-amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "/boot/config-6.1.0-17-amd64", config.Source)
+}
+
+func TestKernelConfigFromModules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"usr/lib/modules/6.8.9-300.fc40.x86_64/config": {Data: []byte("CONFIG_HZ=1000\nCONFIG_BPF_SYSCALL=y\n")},
+	}
+	config, err := GetKernelConfigFromFS(fsys, "6.8.9-300.fc40.x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "/usr/lib/modules/6.8.9-300.fc40.x86_64/config", config.Source)
+	expectEqualStrings(t, "1000", config.Value("CONFIG_HZ"))
+}
+
+func TestKernelConfigMissing(t *testing.T) {
+	fsys := fstest.MapFS{
+		"boot/config-6.1.0-18-amd64": {Data: []byte("CONFIG_HZ=100\n")},
+	}
+	if _, err := GetKernelConfigFromFS(fsys, "6.1.0-17-amd64"); err == nil {
+		t.Errorf("Expected an error for a kernel without configuration")
+	}
+	if _, err := GetKernelConfigFromFS(fstest.MapFS{}, ""); err == nil {
+		t.Errorf("Expected an error without any kernel configuration")
+	}
+}
+
+func TestKernelConfigErrors(t *testing.T) {
+	config, err := parseKernelConfig([]byte("CONFIG_HZ=250\nHZ=250\nCONFIG_LOCALVERSION=\"unterminated\nCONFIG_BPF_SYSCALL=y\n"))
+	if err == nil {
+		t.Fatalf("Expected an error for invalid lines")
+	}
+	expectEqualStrings(t, `line 2: not a kernel option [HZ=250]; line 3: invalid string ["unterminated]`, err.Error())
+	expectEqualStrings(t, "250", config.Value("CONFIG_HZ"))
+	expectEqualStrings(t, "y", config.Value("CONFIG_BPF_SYSCALL"))
+
+	if _, err := parseKernelConfig([]byte{0x1f, 0x8b, 0x08, 0x00}); err == nil {
+		t.Errorf("Expected an error for truncated gzip data")
+	}
+
+	fsys := fstest.MapFS{
+		"boot/config-6.1.0-18-amd64": {Data: []byte("CONFIG_HZ=250\nbad\n")},
+	}
+	_, err = GetKernelConfigFromFS(fsys, "6.1.0-18-amd64")
+	if err == nil {
+		t.Fatalf("Expected an error for an invalid configuration")
+	}
+	expectEqualStrings(t, "/boot/config-6.1.0-18-amd64: line 2: not a kernel option [bad]", err.Error())
+}
@@ -0,0 +1,282 @@
+package osinfo
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// Distribution specific release files, used when there is no os-release.
+// Derivatives come before the distributions whose files they also ship
+// (CentOS has /etc/redhat-release, Ubuntu has /etc/debian_version etc).
+var legacyReleaseFiles = []struct {
+	path  string
+	parse func(info *OSInfo, contents string) error
+}{
+	{"etc/centos-release", parseRedHatRelease},
+	{"etc/redhat-release", parseRedHatRelease},
+	{"etc/SuSE-release", parseSuSERelease},
+	{"etc/openwrt_release", parseOpenWrtRelease},
+	{"etc/alpine-release", parseAlpineRelease},
+	{"etc/arch-release", parseArchRelease},
+	{"etc/gentoo-release", parseGentooRelease},
+	{"etc/slackware-version", parseSlackwareVersion},
+	{"etc/debian_version", parseDebianVersion},
+}
+
+// Maps the distribution name in /etc/redhat-release to its os-release ID
+var redHatReleaseIDs = []struct {
+	prefix string
+	id     string
+}{
+	{"CentOS", "centos"},
+	{"Red Hat Enterprise Linux", "rhel"},
+	{"Fedora", "fedora"},
+	{"Scientific Linux", "scientific"},
+	{"Rocky Linux", "rocky"},
+	{"AlmaLinux", "almalinux"},
+	{"Oracle Linux", "ol"},
+	{"CloudLinux", "cloudlinux"},
+	{"Amazon Linux", "amzn"},
+}
+
+// Distribution specific files holding a more precise version than VERSION_ID.
+// RHEL derivatives are matched through ID_LIKE.
+var pointVersionFiles = []struct {
+	id   string
+	path string
+}{
+	{"debian", "etc/debian_version"},
+	{"alpine", "etc/alpine-release"},
+	{"ol", "etc/oracle-release"},
+	{"rocky", "etc/rocky-release"},
+	{"almalinux", "etc/almalinux-release"},
+	{"centos", "etc/centos-release"},
+	{"rhel", "etc/redhat-release"},
+}
+
+// The version in a point version file, such as "12.5" or
+// "Rocky Linux release 9.3 (Blue Onyx)"
+var pointVersionRE = regexp.MustCompile(`^(?:.*\srelease\s+)?(\d[\w.]*)`)
+
+// parseLegacyReleaseFiles fills the empty fields of info from the first
+// legacy release file that can be parsed. found reports whether any file
+// was usable.
+func parseLegacyReleaseFiles(fsys fs.FS, info *OSInfo) (found bool, err error) {
+	for _, file := range legacyReleaseFiles {
+		contents, readErr := readTextFile(fsys, file.path)
+		if readErr != nil {
+			continue
+		}
+		if parseErr := file.parse(info, contents); parseErr != nil {
+			if err == nil {
+				err = fmt.Errorf("/%v: %v", file.path, parseErr)
+			}
+			continue
+		}
+		return true, nil
+	}
+	return
+}
+
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// Examples:
+//
+//	CentOS release 6.10 (Final)
+//	Red Hat Enterprise Linux Server release 7.9 (Maipo)
+//	Fedora release 20 (Heisenbug)
+func parseRedHatRelease(info *OSInfo, contents string) error {
+	re := regexp.MustCompile(`^(.+?)\s+release\s+(\S+)(?:\s+\(([^)]*)\))?`)
+	found := re.FindStringSubmatch(strings.TrimSpace(contents))
+	if len(found) == 0 {
+		return fmt.Errorf("Could not parse release [%v]", strings.TrimSpace(contents))
+	}
+
+	name := found[1]
+	id := strings.ToLower(strings.Fields(name)[0])
+	for _, candidate := range redHatReleaseIDs {
+		if strings.HasPrefix(name, candidate.prefix) {
+			id = candidate.id
+			break
+		}
+	}
+
+	setIfEmpty(&info.ID, id)
+	setIfEmpty(&info.Name, name)
+	setIfEmpty(&info.Version, found[2])
+	// CentOS uses placeholders instead of codenames
+	if codename := found[3]; codename != "Final" && codename != "Core" {
+		setIfEmpty(&info.Codename, codename)
+	}
+	return nil
+}
+
+// Example:
+//
+//	SUSE Linux Enterprise Server 11 (x86_64)
+//	VERSION = 11
+//	PATCHLEVEL = 4
+func parseSuSERelease(info *OSInfo, contents string) error {
+	lines := strings.Split(strings.TrimSpace(contents), "\n")
+	re := regexp.MustCompile(`^(.+?)\s+[\d.]+\s*(?:\(.*\))?$`)
+	found := re.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if len(found) == 0 {
+		return fmt.Errorf("Could not parse release [%v]", lines[0])
+	}
+	name := found[1]
+
+	keyvalues := make(map[string]string)
+	for _, line := range lines[1:] {
+		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+			keyvalues[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	id := "suse"
+	switch {
+	case strings.HasPrefix(name, "openSUSE"):
+		id = "opensuse"
+	case strings.Contains(name, "Enterprise Server"):
+		id = "sles"
+	case strings.Contains(name, "Enterprise Desktop"):
+		id = "sled"
+	}
+
+	version := keyvalues["VERSION"]
+	if patchLevel := keyvalues["PATCHLEVEL"]; patchLevel != "" && patchLevel != "0" {
+		version = version + "." + patchLevel
+	}
+
+	setIfEmpty(&info.ID, id)
+	setIfEmpty(&info.Name, name)
+	setIfEmpty(&info.Version, version)
+	setIfEmpty(&info.Codename, keyvalues["CODENAME"])
+	return nil
+}
+
+func parseOpenWrtRelease(info *OSInfo, contents string) error {
+	keyvalues, err := parseShellAssignments(contents)
+	if keyvalues["DISTRIB_ID"] == "" {
+		if err == nil {
+			err = fmt.Errorf("Missing DISTRIB_ID")
+		}
+		return err
+	}
+
+	setIfEmpty(&info.ID, strings.ToLower(keyvalues["DISTRIB_ID"]))
+	setIfEmpty(&info.Name, keyvalues["DISTRIB_ID"])
+	setIfEmpty(&info.Version, keyvalues["DISTRIB_RELEASE"])
+	setIfEmpty(&info.Codename, keyvalues["DISTRIB_CODENAME"])
+	return nil
+}
+
+func parseAlpineRelease(info *OSInfo, contents string) error {
+	setIfEmpty(&info.ID, "alpine")
+	setIfEmpty(&info.Name, "Alpine Linux")
+	setIfEmpty(&info.Version, strings.TrimSpace(contents))
+	return nil
+}
+
+// Arch is a rolling release, and the file is normally empty.
+func parseArchRelease(info *OSInfo, contents string) error {
+	setIfEmpty(&info.ID, "arch")
+	setIfEmpty(&info.Name, "Arch Linux")
+	return nil
+}
+
+// Example: Gentoo Base System release 2.7
+func parseGentooRelease(info *OSInfo, contents string) error {
+	setIfEmpty(&info.ID, "gentoo")
+	setIfEmpty(&info.Name, "Gentoo")
+
+	re := regexp.MustCompile(`release\s+(\S+)`)
+	if found := re.FindStringSubmatch(contents); len(found) > 0 {
+		setIfEmpty(&info.Version, found[1])
+	}
+	return nil
+}
+
+// Example: Slackware 14.2
+func parseSlackwareVersion(info *OSInfo, contents string) error {
+	re := regexp.MustCompile(`^Slackware\s+(\S+)`)
+	found := re.FindStringSubmatch(strings.TrimSpace(contents))
+	if len(found) == 0 {
+		return fmt.Errorf("Could not parse version [%v]", strings.TrimSpace(contents))
+	}
+
+	setIfEmpty(&info.ID, "slackware")
+	setIfEmpty(&info.Name, "Slackware")
+	setIfEmpty(&info.Version, strings.TrimSuffix(found[1], "+"))
+	return nil
+}
+
+// Stable releases contain a version number such as "9.13", while testing and
+// unstable contain a codename such as "bullseye/sid".
+func parseDebianVersion(info *OSInfo, contents string) error {
+	contents = strings.TrimSpace(contents)
+	if contents == "" {
+		return fmt.Errorf("Empty version")
+	}
+
+	setIfEmpty(&info.ID, "debian")
+	setIfEmpty(&info.Name, "Debian GNU/Linux")
+	if contents[0] >= '0' && contents[0] <= '9' {
+		setIfEmpty(&info.Version, contents)
+	} else {
+		setIfEmpty(&info.Codename, strings.Split(contents, "/")[0])
+	}
+	return nil
+}
+
+// enrichPointVersion sets info.PointVersion to the most precise version that
+// can be found, such as "12.5" for Debian where VERSION_ID is "12".
+// A candidate is only used if it refines Version, otherwise Version is kept.
+func enrichPointVersion(fsys fs.FS, info *OSInfo) {
+	info.PointVersion = info.Version
+	if info.Version == "" {
+		return
+	}
+
+	refines := func(candidate string) bool {
+		return candidate == info.Version || strings.HasPrefix(candidate, info.Version+".")
+	}
+
+	// Ubuntu has it in VERSION, such as "22.04.3 LTS (Jammy Jellyfish)"
+	if info.ID == "ubuntu" {
+		if fields := strings.Fields(info.OSRelease.Version); len(fields) > 0 && refines(fields[0]) {
+			info.PointVersion = fields[0]
+		}
+		return
+	}
+
+	ids := append([]string{info.ID}, info.OSRelease.IDLike...)
+	for _, file := range pointVersionFiles {
+		if !containsString(ids, file.id) {
+			continue
+		}
+		contents, err := readTextFile(fsys, file.path)
+		if err != nil {
+			continue
+		}
+		found := pointVersionRE.FindStringSubmatch(strings.TrimSpace(contents))
+		if len(found) > 0 && refines(found[1]) {
+			info.PointVersion = found[1]
+			return
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package osinfo
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func legacyFS(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for path, contents := range files {
+		fsys[path] = &fstest.MapFile{Data: []byte(contents)}
+	}
+	return fsys
+}
+
+func expectLegacyInfo(t *testing.T, files map[string]string, id, name, version, codename string) {
+	info, err := GetOSInfoFromFS(legacyFS(files))
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectEqualStrings(t, id, info.ID)
+	expectEqualStrings(t, name, info.Name)
+	expectEqualStrings(t, version, info.Version)
+	expectEqualStrings(t, codename, info.Codename)
+}
+
+func TestLegacyCentOS6(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/centos-release": "CentOS release 6.10 (Final)\n",
+		"etc/redhat-release": "CentOS release 6.10 (Final)\n",
+		"etc/system-release": "CentOS release 6.10 (Final)\n",
+	}, "centos", "CentOS", "6.10", "")
+}
+
+func TestLegacyRHEL7(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/redhat-release": "Red Hat Enterprise Linux Server release 7.9 (Maipo)\n",
+	}, "rhel", "Red Hat Enterprise Linux Server", "7.9", "Maipo")
+}
+
+func TestLegacyFedora(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/redhat-release": "Fedora release 20 (Heisenbug)\n",
+	}, "fedora", "Fedora", "20", "Heisenbug")
+}
+
+func TestLegacySLES11(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/SuSE-release": `SUSE Linux Enterprise Server 11 (x86_64)
+VERSION = 11
+PATCHLEVEL = 4
+`,
+	}, "sles", "SUSE Linux Enterprise Server", "11.4", "")
+}
+
+func TestLegacyOpenSUSE(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/SuSE-release": `openSUSE 13.2 (x86_64)
+VERSION = 13.2
+CODENAME = Harlequin
+# /etc/SuSE-release is deprecated and will be removed in a future service pack or release.
+`,
+	}, "opensuse", "openSUSE", "13.2", "Harlequin")
+}
+
+func TestLegacyOpenWrt(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/openwrt_release": `DISTRIB_ID='OpenWrt'
+DISTRIB_RELEASE='19.07.3'
+DISTRIB_REVISION='r11063-85e04e9f46'
+DISTRIB_TARGET='x86/64'
+DISTRIB_ARCH='x86_64'
+DISTRIB_DESCRIPTION='OpenWrt 19.07.3 r11063-85e04e9f46'
+DISTRIB_TAINTS=''
+`,
+	}, "openwrt", "OpenWrt", "19.07.3", "")
+}
+
+func TestLegacyAlpine(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/alpine-release": "3.8.0\n",
+	}, "alpine", "Alpine Linux", "3.8.0", "")
+}
+
+func TestLegacyArch(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/arch-release": "",
+	}, "arch", "Arch Linux", "", "")
+}
+
+func TestLegacyGentoo(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/gentoo-release": "Gentoo Base System release 2.7\n",
+	}, "gentoo", "Gentoo", "2.7", "")
+}
+
+func TestLegacySlackware(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/slackware-version": "Slackware 14.2\n",
+	}, "slackware", "Slackware", "14.2", "")
+}
+
+func TestLegacyDebian(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/debian_version": "7.11\n",
+	}, "debian", "Debian GNU/Linux", "7.11", "")
+}
+
+func TestLegacyDebianTesting(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/debian_version": "bullseye/sid\n",
+	}, "debian", "Debian GNU/Linux", "", "bullseye")
+}
+
+func TestLegacyUbuntuPrefersLSBRelease(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/lsb-release": `DISTRIB_ID=Ubuntu
+DISTRIB_RELEASE=12.04
+DISTRIB_CODENAME=precise
+DISTRIB_DESCRIPTION="Ubuntu 12.04.5 LTS"
+`,
+		"etc/debian_version": "wheezy/sid\n",
+	}, "Ubuntu", "Ubuntu 12.04.5 LTS", "12.04", "precise")
+}
+
+func TestLegacyFilesIgnoredWithOSRelease(t *testing.T) {
+	expectLegacyInfo(t, map[string]string{
+		"etc/os-release":     "ID=centos\nNAME=\"CentOS Linux\"\nVERSION_ID=\"7\"\n",
+		"etc/redhat-release": "CentOS Linux release 7.9.2009 (Core)\n",
+	}, "centos", "CentOS Linux", "7", "")
+}
+
+func TestLegacyUnparseableFile(t *testing.T) {
+	_, err := GetOSInfoFromFS(legacyFS(map[string]string{
+		"etc/redhat-release": "garbage\n",
+	}))
+	if err == nil {
+		t.Error("Expected an error for an unparseable release file")
+	}
+}
+
+func expectPointVersion(t *testing.T, files map[string]string, version, pointVersion string) {
+	info, err := GetOSInfoFromFS(legacyFS(files))
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectEqualStrings(t, version, info.Version)
+	expectEqualStrings(t, pointVersion, info.PointVersion)
+}
+
+func TestPointVersionDebian(t *testing.T) {
+	expectPointVersion(t, map[string]string{
+		"etc/os-release":     "ID=debian\nVERSION_ID=\"12\"\nVERSION=\"12 (bookworm)\"\n",
+		"etc/debian_version": "12.5\n",
+	}, "12", "12.5")
+}
+
+func TestPointVersionRHEL(t *testing.T) {
+	expectPointVersion(t, map[string]string{
+		"etc/os-release":     "ID=\"rhel\"\nID_LIKE=\"fedora\"\nVERSION_ID=\"8.9\"\n",
+		"etc/redhat-release": "Red Hat Enterprise Linux release 8.9 (Ootpa)\n",
+	}, "8.9", "8.9")
+}
+
+func TestPointVersionCentOS7(t *testing.T) {
+	expectPointVersion(t, map[string]string{
+		"etc/os-release":     "ID=\"centos\"\nID_LIKE=\"rhel fedora\"\nVERSION_ID=\"7\"\n",
+		"etc/centos-release": "CentOS Linux release 7.9.2009 (Core)\n",
+		"etc/redhat-release": "CentOS Linux release 7.9.2009 (Core)\n",
+	}, "7", "7.9.2009")
+}
+
+func TestPointVersionRocky(t *testing.T) {
+	expectPointVersion(t, map[string]string{
+		"etc/os-release":     "ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n",
+		"etc/rocky-release":  "Rocky Linux release 9.3 (Blue Onyx)\n",
+		"etc/redhat-release": "Rocky Linux release 9.3 (Blue Onyx)\n",
+	}, "9.3", "9.3")
+}
+
+func TestPointVersionAlpine(t *testing.T) {
+	expectPointVersion(t, map[string]string{
+		"etc/os-release":     "ID=alpine\nVERSION_ID=3.19\n",
+		"etc/alpine-release": "3.19.1\n",
+	}, "3.19", "3.19.1")
+}
+
+func TestPointVersionUbuntu(t *testing.T) {
+	expectPointVersion(t, map[string]string{
+		"etc/os-release":     "ID=ubuntu\nVERSION_ID=\"22.04\"\nVERSION=\"22.04.3 LTS (Jammy Jellyfish)\"\n",
+		"etc/debian_version": "bookworm/sid\n",
+	}, "22.04", "22.04.3")
+}
+
+func TestPointVersionIgnoresMismatch(t *testing.T) {
+	expectPointVersion(t, map[string]string{
+		"etc/os-release":     "ID=debian\nVERSION_ID=\"12\"\n",
+		"etc/debian_version": "11.9\n",
+	}, "12", "12")
+}
+
+func TestPointVersionLegacy(t *testing.T) {
+	expectPointVersion(t, map[string]string{
+		"etc/centos-release": "CentOS release 6.10 (Final)\n",
+	}, "6.10", "6.10")
+}
@@ -0,0 +1,187 @@
+package osinfo
+
+import (
+	"bytes"
+	_ "embed" // For the lifecycle database
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Lifecycle statuses, as reported in Lifecycle.Status
+const (
+	LifecycleUnknown         = "unknown"
+	LifecycleSupported       = "supported"
+	LifecycleExtendedSupport = "extended-support"
+	LifecycleEndOfLife       = "end-of-life"
+)
+
+// Lifecycle describes where a release is in its support lifecycle.
+// Dates are the last day of each phase, and are zero when unknown or not yet
+// announced.
+type Lifecycle struct {
+	Status               string
+	Release              time.Time
+	EndOfSupport         time.Time
+	EndOfExtendedSupport time.Time
+	// Long term support release
+	LTS bool
+	// "os-release" when EndOfSupport comes from SUPPORT_END, "database"
+	// when it comes from the lifecycle database, empty when unknown.
+	Source string
+}
+
+// The lifecycle database shipped with the package. It can be replaced at
+// runtime with LoadLifecycleData().
+//
+//go:embed lifecycle.json
+var embeddedLifecycleData []byte
+
+type lifecycleRelease struct {
+	ID string `json:"id"`
+	// Only match OS names containing this string (such as "Server")
+	Name string `json:"name"`
+	// Matched against OSInfo.ParsedVersion(), using only the components
+	// present here ("8" matches 8.9)
+	Version              string `json:"version"`
+	Release              string `json:"release"`
+	EndOfSupport         string `json:"end_of_support"`
+	EndOfExtendedSupport string `json:"end_of_extended_support"`
+	LTS                  bool   `json:"lts"`
+
+	lifecycle Lifecycle
+}
+
+type lifecycleData struct {
+	// Maps derivatives to the distribution whose lifecycle they follow
+	Aliases  map[string]string  `json:"aliases"`
+	Releases []lifecycleRelease `json:"releases"`
+}
+
+var lifecycleLock sync.Mutex
+var lifecycleDatabase *lifecycleData
+
+func parseLifecycleDate(field string, value string) (date time.Time, err error) {
+	if value == "" {
+		return
+	}
+	if date, err = time.Parse("2006-01-02", value); err != nil {
+		err = fmt.Errorf("%v: Invalid date [%v]", field, value)
+	}
+	return
+}
+
+func parseLifecycleData(r io.Reader) (*lifecycleData, error) {
+	data := new(lifecycleData)
+	if err := json.NewDecoder(r).Decode(data); err != nil {
+		return nil, err
+	}
+
+	for i := range data.Releases {
+		release := &data.Releases[i]
+		if release.ID == "" {
+			return nil, fmt.Errorf("Release %v: Missing id", i)
+		}
+		if _, err := ParseVersion(release.Version); err != nil {
+			return nil, fmt.Errorf("Release %v (%v): %v", i, release.ID, err)
+		}
+
+		var err error
+		lifecycle := &release.lifecycle
+		if lifecycle.Release, err = parseLifecycleDate("release", release.Release); err == nil {
+			if lifecycle.EndOfSupport, err = parseLifecycleDate("end_of_support", release.EndOfSupport); err == nil {
+				lifecycle.EndOfExtendedSupport, err = parseLifecycleDate("end_of_extended_support", release.EndOfExtendedSupport)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Release %v (%v %v): %v", i, release.ID, release.Version, err)
+		}
+		lifecycle.LTS = release.LTS
+		lifecycle.Source = "database"
+	}
+	return data, nil
+}
+
+// LoadLifecycleData replaces the lifecycle database with the JSON document
+// read from r, which uses the same format as the embedded lifecycle.json.
+func LoadLifecycleData(r io.Reader) error {
+	data, err := parseLifecycleData(r)
+	if err != nil {
+		return err
+	}
+
+	lifecycleLock.Lock()
+	defer lifecycleLock.Unlock()
+	lifecycleDatabase = data
+	return nil
+}
+
+func currentLifecycleData() *lifecycleData {
+	lifecycleLock.Lock()
+	defer lifecycleLock.Unlock()
+	if lifecycleDatabase == nil {
+		data, err := parseLifecycleData(bytes.NewReader(embeddedLifecycleData))
+		if err != nil {
+			// Only possible if lifecycle.json is broken, which the tests catch
+			data = new(lifecycleData)
+		}
+		lifecycleDatabase = data
+	}
+	return lifecycleDatabase
+}
+
+func (data *lifecycleData) find(info *OSInfo) (Lifecycle, bool) {
+	version, err := info.ParsedVersion()
+	if err != nil {
+		return Lifecycle{}, false
+	}
+	id := strings.ToLower(info.ID)
+	if alias, ok := data.Aliases[id]; ok {
+		id = alias
+	}
+
+	for _, release := range data.Releases {
+		if release.ID != id || !strings.Contains(info.Name, release.Name) {
+			continue
+		}
+		if result, err := compareVersionPrefix(version, release.Version); err == nil && result == 0 {
+			return release.lifecycle, true
+		}
+	}
+	return Lifecycle{}, false
+}
+
+// Lifecycle returns the support status of the OS release at the given time.
+// SUPPORT_END from os-release takes precedence over the lifecycle database.
+// The status is LifecycleUnknown if the release isn't in the database.
+func (info *OSInfo) Lifecycle(at time.Time) Lifecycle {
+	lifecycle, found := currentLifecycleData().find(info)
+
+	if supportEnd, err := parseLifecycleDate("SUPPORT_END", info.OSRelease.SupportEnd); err == nil && !supportEnd.IsZero() {
+		lifecycle.EndOfSupport = supportEnd
+		lifecycle.Source = "os-release"
+		found = true
+	}
+
+	if !found {
+		lifecycle.Status = LifecycleUnknown
+		return lifecycle
+	}
+
+	// The end dates are the last day of each phase
+	ended := func(end time.Time) bool {
+		return !end.IsZero() && !at.Before(end.AddDate(0, 0, 1))
+	}
+	switch {
+	case !ended(lifecycle.EndOfSupport):
+		lifecycle.Status = LifecycleSupported
+	case !lifecycle.EndOfExtendedSupport.IsZero() && !ended(lifecycle.EndOfExtendedSupport):
+		lifecycle.Status = LifecycleExtendedSupport
+	default:
+		lifecycle.Status = LifecycleEndOfLife
+	}
+	return lifecycle
+}
@@ -0,0 +1,149 @@
+package osinfo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lifecycleDate(date string) time.Time {
+	t, _ := time.Parse("2006-01-02", date)
+	return t
+}
+
+func expectLifecycle(t *testing.T, info *OSInfo, at string, status string) Lifecycle {
+	lifecycle := info.Lifecycle(lifecycleDate(at))
+	if lifecycle.Status != status {
+		t.Errorf("%v %v at %v: Expected [%v] but got [%v]", info.ID, info.Version, at, status, lifecycle.Status)
+	}
+	return lifecycle
+}
+
+func TestEmbeddedLifecycleDataIsValid(t *testing.T) {
+	data, err := parseLifecycleData(bytes.NewReader(embeddedLifecycleData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, release := range data.Releases {
+		lifecycle := release.lifecycle
+		if lifecycle.Release.IsZero() {
+			t.Errorf("%v %v: Missing release date", release.ID, release.Version)
+		}
+		if !lifecycle.EndOfSupport.IsZero() && lifecycle.EndOfSupport.Before(lifecycle.Release) {
+			t.Errorf("%v %v: Support ends before the release", release.ID, release.Version)
+		}
+		if !lifecycle.EndOfExtendedSupport.IsZero() && lifecycle.EndOfExtendedSupport.Before(lifecycle.EndOfSupport) {
+			t.Errorf("%v %v: Extended support ends before standard support", release.ID, release.Version)
+		}
+	}
+}
+
+func TestLifecycleUbuntuLTS(t *testing.T) {
+	info := &OSInfo{Family: "linux", ID: "ubuntu", Version: "18.04", PointVersion: "18.04.6"}
+	lifecycle := expectLifecycle(t, info, "2022-01-01", LifecycleSupported)
+	if !lifecycle.LTS {
+		t.Error("Expected 18.04 to be an LTS release")
+	}
+	expectEqualStrings(t, "database", lifecycle.Source)
+	expectLifecycle(t, info, "2023-05-31", LifecycleSupported)
+	expectLifecycle(t, info, "2023-06-01", LifecycleExtendedSupport)
+	expectLifecycle(t, info, "2028-05-01", LifecycleEndOfLife)
+}
+
+func TestLifecycleRHELClone(t *testing.T) {
+	info := &OSInfo{Family: "linux", ID: "rocky", Version: "8.9"}
+	lifecycle := expectLifecycle(t, info, "2026-01-01", LifecycleSupported)
+	if !lifecycle.EndOfSupport.Equal(lifecycleDate("2029-05-31")) {
+		t.Errorf("Unexpected end of support %v", lifecycle.EndOfSupport)
+	}
+}
+
+func TestLifecycleInterimRelease(t *testing.T) {
+	info := &OSInfo{Family: "linux", ID: "ubuntu", Version: "23.10"}
+	expectLifecycle(t, info, "2024-08-01", LifecycleEndOfLife)
+}
+
+func TestLifecycleMacOS(t *testing.T) {
+	expectLifecycle(t, &OSInfo{Family: "darwin", ID: "darwin", Version: "12.7.4"}, "2025-01-01", LifecycleEndOfLife)
+	// No end of support announced
+	expectLifecycle(t, &OSInfo{Family: "darwin", ID: "darwin", Version: "15.1"}, "2030-01-01", LifecycleSupported)
+}
+
+func TestLifecycleWindows(t *testing.T) {
+	windows10 := &OSInfo{Family: "windows", ID: "windows", Name: "Windows 10 Pro", Version: "10.0", Build: "19045"}
+	expectLifecycle(t, windows10, "2026-01-01", LifecycleExtendedSupport)
+
+	server := &OSInfo{Family: "windows", ID: "windows", Name: "Windows Server 2025 Datacenter", Version: "10.0", Build: "26100"}
+	lifecycle := expectLifecycle(t, server, "2030-01-01", LifecycleExtendedSupport)
+	if !lifecycle.LTS {
+		t.Error("Expected Server 2025 to be an LTS release")
+	}
+
+	windows11 := &OSInfo{Family: "windows", ID: "windows", Name: "Windows 11 Pro", Version: "10.0", Build: "26100"}
+	expectLifecycle(t, windows11, "2027-01-01", LifecycleEndOfLife)
+
+	// Client releases don't match servers with the same build
+	insider := &OSInfo{Family: "windows", ID: "windows", Name: "Windows Server Datacenter", Version: "10.0", Build: "22631"}
+	expectLifecycle(t, insider, "2025-01-01", LifecycleUnknown)
+}
+
+func TestLifecycleRecentReleases(t *testing.T) {
+	lifecycle := expectLifecycle(t, &OSInfo{Family: "linux", ID: "ubuntu", Version: "26.04"}, "2026-10-18", LifecycleSupported)
+	if !lifecycle.LTS {
+		t.Error("Expected Ubuntu 26.04 to be an LTS release")
+	}
+	expectLifecycle(t, &OSInfo{Family: "linux", ID: "fedora", Version: "43"}, "2026-10-18", LifecycleSupported)
+	expectLifecycle(t, &OSInfo{Family: "linux", ID: "fedora", Version: "44"}, "2026-10-18", LifecycleSupported)
+	expectLifecycle(t, &OSInfo{Family: "linux", ID: "alpine", Version: "3.23.2"}, "2026-10-18", LifecycleSupported)
+	expectLifecycle(t, &OSInfo{Family: "freebsd", ID: "freebsd", Version: "15.0-RELEASE-p3"}, "2026-10-18", LifecycleEndOfLife)
+}
+
+func TestLifecyclePrefersSupportEnd(t *testing.T) {
+	info := &OSInfo{Family: "linux", ID: "fedora", Version: "40", OSRelease: OSRelease{SupportEnd: "2025-05-28"}}
+	lifecycle := expectLifecycle(t, info, "2025-05-20", LifecycleSupported)
+	expectEqualStrings(t, "os-release", lifecycle.Source)
+	expectLifecycle(t, info, "2025-05-29", LifecycleEndOfLife)
+
+	// Even for releases missing from the database
+	unknown := &OSInfo{Family: "linux", ID: "someos", Version: "1", OSRelease: OSRelease{SupportEnd: "2030-12-31"}}
+	expectLifecycle(t, unknown, "2025-01-01", LifecycleSupported)
+}
+
+func TestLifecycleUnknown(t *testing.T) {
+	expectLifecycle(t, &OSInfo{Family: "linux", ID: "someos", Version: "1"}, "2025-01-01", LifecycleUnknown)
+	expectLifecycle(t, &OSInfo{Family: "linux", ID: "ubuntu", Version: "12.10"}, "2025-01-01", LifecycleUnknown)
+	expectLifecycle(t, &OSInfo{Family: "linux", ID: "debian"}, "2025-01-01", LifecycleUnknown)
+}
+
+func TestLoadLifecycleData(t *testing.T) {
+	defer func() {
+		if err := LoadLifecycleData(bytes.NewReader(embeddedLifecycleData)); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	err := LoadLifecycleData(strings.NewReader(`{
+		"aliases": {"derivative": "someos"},
+		"releases": [{"id": "someos", "version": "1", "release": "2020-01-01", "end_of_support": "2021-01-01"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectLifecycle(t, &OSInfo{Family: "linux", ID: "derivative", Version: "1.5"}, "2022-01-01", LifecycleEndOfLife)
+	expectLifecycle(t, &OSInfo{Family: "linux", ID: "ubuntu", Version: "22.04"}, "2022-01-01", LifecycleUnknown)
+}
+
+func TestLoadLifecycleDataErrors(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"releases": [{"version": "1", "release": "2020-01-01"}]}`,
+		`{"releases": [{"id": "someos", "version": "one", "release": "2020-01-01"}]}`,
+		`{"releases": [{"id": "someos", "version": "1", "release": "01/01/2020"}]}`,
+	} {
+		if err := LoadLifecycleData(strings.NewReader(data)); err == nil {
+			t.Errorf("Expected an error loading [%v]", data)
+		}
+	}
+}
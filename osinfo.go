@@ -9,29 +9,20 @@ package osinfo
 import (
 	"encoding/hex"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
+	"os"
+	"path"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
-// Note: This must be updated with every new Mac OS release.
+// Note: maccodenames.txt must be updated with every new macOS release.
 //
 //	There is no other reliable way to get the "marketing" name from a mac.
-var macCodeNames = map[string]string{
-	// Mininum 10.10 (https://github.com/golang/go/wiki/MinimumRequirements)
-	"10.10": "Yosemite",
-	"10.11": "El Capitan",
-	"10.12": "Sierra",
-	"10.13": "High Sierra",
-	"10.14": "Mojave",
-	"10.15": "Catalina",
-	"10.16": "Big Sur", // Big Sur is both 10.16 and 11.0
-	"11.0":  "Big Sur", // See https://en.wikipedia.org/wiki/MacOS_Big_Sur#Development_history
-	"12.0":  "Monterey",
-	"13.0":  "Ventura",
-	"14.0":  "Sonoma",
-}
+//
+//go:generate go run gen_maccodenames.go
 
 type OSInfo struct {
 	Family       string
@@ -42,6 +33,31 @@ type OSInfo struct {
 	Version      string
 	Build        string
 	IsWSL        bool
+
+	// Rapid Security Response version suffix, such as "(a)" (macOS only)
+	VersionExtra string
+
+	// The family of the distribution, such as "debian" for Ubuntu (Linux only)
+	DistroFamily string
+	// The most precise version available, such as "12.5" where Version is
+	// "12" (Linux only)
+	PointVersion string
+
+	// The native package manager (such as "apt" or "dnf") and the format of
+	// its binary packages (such as "deb" or "rpm")
+	PackageManager string
+	PackageFormat  string
+
+	// The full contents of os-release (Linux only)
+	OSRelease OSRelease
+	// Merged systemd-sysext and systemd-confext images (Linux only)
+	Extensions []Extension
+	// The running kernel (Linux, FreeBSD and macOS)
+	Kernel Kernel
+	// The container the OS runs in (Linux only, and not from GetOSInfoFromFS)
+	Container Container
+	// Edition, installation type and update level (Windows only)
+	Windows WindowsDetails
 }
 
 // GetOSInfo gets information about the current operating system.
@@ -73,15 +89,85 @@ func GetOSInfo() (*OSInfo, error) {
 	}
 }
 
-func readTextFile(path string) (result string, err error) {
+// GetOSInfoFromFS gets information about the Linux, macOS or Windows system
+// whose root filesystem is fsys, such as a chroot, a mounted disk image or an
+// extracted container image. Use os.DirFS("/") to inspect the running system.
+// Architecture is left empty because it cannot be determined from the files,
+// and Container because files such as /.dockerenv in an image or a mounted
+// root don't mean that it runs in a container.
+func GetOSInfoFromFS(fsys fs.FS) (*OSInfo, error) {
+	if _, err := fs.Stat(fsys, macSystemVersionPath); err == nil {
+		return getOSInfoMacFromFS(fsys)
+	}
+	if hivePath, ok := findPathFold(fsys, windowsSoftwareHivePath); ok {
+		hive, err := fs.ReadFile(fsys, hivePath)
+		if err != nil {
+			return &OSInfo{Family: "windows", ID: "windows"}, err
+		}
+		return GetOSInfoFromRegistryHive(hive)
+	}
+	return getOSInfoLinuxFromFS(fsys)
+}
+
+const windowsSoftwareHivePath = "Windows/System32/config/SOFTWARE"
+
+// GetOSInfoFromRegistryHive gets information about a Windows system from its
+// SOFTWARE registry hive file (Windows\System32\config\SOFTWARE), for example
+// from a backup. Architecture is left empty.
+func GetOSInfoFromRegistryHive(hive []byte) (info *OSInfo, err error) {
+	info = &OSInfo{Family: "windows", ID: "windows"}
+	var values registryValues
+	values, err = readRegistryHiveKey(hive, `Microsoft\Windows NT\CurrentVersion`)
+	if err != nil {
+		return
+	}
+	err = populateWindowsInfo(info, values)
+	return
+}
+
+// findPathFold finds a file ignoring case, since a Windows filesystem mounted
+// on another OS keeps whatever case its files were created with.
+func findPathFold(fsys fs.FS, name string) (string, bool) {
+	found := "."
+	for _, component := range strings.Split(name, "/") {
+		entries, err := fs.ReadDir(fsys, found)
+		if err != nil {
+			return "", false
+		}
+		match := ""
+		for _, entry := range entries {
+			if strings.EqualFold(entry.Name(), component) {
+				match = entry.Name()
+				break
+			}
+		}
+		if match == "" {
+			return "", false
+		}
+		found = path.Join(found, match)
+	}
+	return found, true
+}
+
+func readTextFile(fsys fs.FS, path string) (result string, err error) {
 	var bytes []byte
-	bytes, err = ioutil.ReadFile(path)
+	bytes, err = fs.ReadFile(fsys, path)
 	if err == nil {
 		result = string(bytes)
 	}
 	return
 }
 
+// readSysFile reads a single value file, such as those in /proc/sys and
+// /sys, returning an empty string if it can't be read.
+func readSysFile(fsys fs.FS, path string) string {
+	contents, err := readTextFile(fsys, path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(contents)
+}
+
 func hexToInt(hexString string) (int, error) {
 	if len(hexString) < 3 || hexString[:2] != "0x" {
 		return 0, fmt.Errorf("%v: Not a hex number", hexString)
@@ -103,27 +189,75 @@ func hexToInt(hexString string) (int, error) {
 	return accumulator, nil
 }
 
-func extractRegistryString(id string, regCommandOutput string) (string, error) {
-	rePortion := `.*\s+REG_\w+\s+(.+)`
-	re := regexp.MustCompile(fmt.Sprintf("%v%v", id, rePortion))
-	found := re.FindStringSubmatch(regCommandOutput)
-	if len(found) == 0 {
-		return "", fmt.Errorf("Error: Could not parse reg query result: %v", regCommandOutput)
+// registryValues holds the values of a registry key, keyed on their
+// lowercased names since registry value names are case insensitive.
+type registryValues map[string]registryValue
+
+type registryValue struct {
+	name      string
+	valueType string
+	data      string
+}
+
+// parseRegistryValues parses the output of `reg query KEY`, which lists
+// every value of the key followed by its subkeys:
+//
+//	HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+//	    ProductName    REG_SZ    Windows 10 Pro
+//	    UBR    REG_DWORD    0xd77
+//
+//	HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion\AeDebug
+func parseRegistryValues(regCommandOutput string) registryValues {
+	values := make(registryValues)
+	for _, line := range strings.Split(regCommandOutput, "\n") {
+		line = strings.TrimRight(line, "\r")
+		// Values are indented, keys are not
+		if !strings.HasPrefix(line, "    ") {
+			continue
+		}
+		fields := strings.SplitN(line[4:], "    ", 3)
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "REG_") {
+			continue
+		}
+		value := registryValue{name: fields[0], valueType: fields[1]}
+		if len(fields) == 3 {
+			value.data = strings.TrimSpace(fields[2])
+		}
+		values[strings.ToLower(value.name)] = value
 	}
+	return values
+}
+
+func (values registryValues) lookup(id string) (registryValue, bool) {
+	value, ok := values[strings.ToLower(id)]
+	return value, ok
+}
 
-	return found[1], nil
+func (values registryValues) getString(id string) (string, error) {
+	value, ok := values.lookup(id)
+	if !ok {
+		return "", fmt.Errorf("Error: Registry value %v not found", id)
+	}
+	return value.data, nil
 }
 
-func extractRegistryInt(id string, regCommandOutput string) (int, error) {
-	stringValue, err := extractRegistryString(id, regCommandOutput)
+func (values registryValues) getInt(id string) (int, error) {
+	stringValue, err := values.getString(id)
 	if err != nil {
 		return 0, err
 	}
 	return hexToInt(stringValue)
 }
 
-func getRegistryRaw(id string) (string, error) {
-	return readCommandOutput(`C:\Windows\system32\reg.exe`, `query`, `HKLM\SOFTWARE\Microsoft\Windows NT\CurrentVersion`, `/v`, id)
+// getRegistryValues reads every value of the CurrentVersion key with a
+// single reg.exe call, since spawning processes is slow on hosts where
+// every process launch is scanned.
+func getRegistryValues() (registryValues, error) {
+	raw, err := readCommandOutput(`C:\Windows\system32\reg.exe`, `query`, `HKLM\SOFTWARE\Microsoft\Windows NT\CurrentVersion`)
+	if err != nil {
+		return nil, err
+	}
+	return parseRegistryValues(raw), nil
 }
 
 func populateFromRuntime(info *OSInfo) {
@@ -131,8 +265,9 @@ func populateFromRuntime(info *OSInfo) {
 	info.Family = runtime.GOOS
 }
 
-func parseEtcOSRelease(info *OSInfo, contents string) {
-	keyvalues := parseKeyValues(contents)
+func parseEtcOSRelease(info *OSInfo, contents string) error {
+	keyvalues, err := parseShellAssignments(contents)
+	info.OSRelease = newOSRelease(keyvalues)
 
 	if v, ok := keyvalues["ID"]; ok && info.ID == "" {
 		info.ID = v
@@ -149,10 +284,12 @@ func parseEtcOSRelease(info *OSInfo, contents string) {
 	if v, ok := keyvalues["VERSION_CODENAME"]; ok && info.Codename == "" {
 		info.Codename = v
 	}
+
+	return err
 }
 
-func parseEtcLSBRelease(info *OSInfo, contents string) {
-	keyvalues := parseKeyValues(contents)
+func parseEtcLSBRelease(info *OSInfo, contents string) error {
+	keyvalues, err := parseShellAssignments(contents)
 
 	if v, ok := keyvalues["DISTRIB_ID"]; ok && info.ID == "" {
 		info.ID = v
@@ -166,27 +303,30 @@ func parseEtcLSBRelease(info *OSInfo, contents string) {
 	if v, ok := keyvalues["DISTRIB_DESCRIPTION"]; ok && info.Name == "" {
 		info.Name = v
 	}
-}
 
-func parseKeyValues(contents string) (kvmap map[string]string) {
-	kvmap = make(map[string]string)
-	re := regexp.MustCompile(`\b(.+)="?([^"\n]*)"?`)
-	for _, found := range re.FindAllStringSubmatch(contents, -1) {
-		kvmap[found[1]] = found[2]
-	}
-	return
+	return err
 }
 
 func parseMacSWVers(info *OSInfo, productVersion, buildVersion string) error {
 	info.Version = productVersion
 	info.Build = buildVersion
 
-	re := regexp.MustCompile(`\d+\.\d+`)
-	version := re.FindString(info.Version)
-	if len(version) == 0 {
+	re := regexp.MustCompile(`^(\d+)(?:\.(\d+))?`)
+	found := re.FindStringSubmatch(info.Version)
+	if len(found) == 0 {
 		return fmt.Errorf("Could not parse product version [%v]", info.Version)
 	}
-	codeName, ok := macCodeNames[version]
+	major, _ := strconv.Atoi(found[1])
+	minor, _ := strconv.Atoi(found[2])
+
+	info.Name = macProductName(major, minor)
+
+	// Since Big Sur, the codename only depends on the major version
+	key := found[1]
+	if major < 11 {
+		key = fmt.Sprintf("%v.%v", major, minor)
+	}
+	codeName, ok := macCodeNames[key]
 	if ok {
 		info.Codename = codeName
 	} else {
@@ -196,6 +336,19 @@ func parseMacSWVers(info *OSInfo, productVersion, buildVersion string) error {
 	return nil
 }
 
+// The product was renamed from "Mac OS X" to "OS X" in 10.8, and to "macOS"
+// in 10.12.
+func macProductName(major, minor int) string {
+	switch {
+	case major == 10 && minor < 8:
+		return "Mac OS X"
+	case major == 10 && minor < 12:
+		return "OS X"
+	default:
+		return "macOS"
+	}
+}
+
 func parseFreeBSDUname(info *OSInfo, unameV string) error {
 	re := regexp.MustCompile(`(\S+)\s+(\S+)\s+(\S+).*`)
 	found := re.FindStringSubmatch(unameV)
@@ -210,90 +363,225 @@ func parseFreeBSDUname(info *OSInfo, unameV string) error {
 	return nil
 }
 
-func getRegistryString(id string) (string, error) {
-	raw, err := getRegistryRaw(id)
-	if err != nil {
-		return "", err
+func newWindowsInfo() *OSInfo {
+	info := new(OSInfo)
+	populateFromRuntime(info)
+	info.ID = "windows"
+	return info
+}
+
+func getOSInfoWindows() (info *OSInfo, err error) {
+	info = newWindowsInfo()
+	var values registryValues
+	values, err = getRegistryValues()
+	if err == nil {
+		err = populateWindowsInfo(info, values)
 	}
+	if err == nil {
+		return
+	}
+
+	// Try the fallbacks in order, keeping the registry result if all fail
+	var fallbackErrors []string
+	for _, fallback := range windowsFallbacks {
+		fallbackInfo := newWindowsInfo()
+		fallbackErr := fallback.read(fallbackInfo)
+		if fallbackErr == nil {
+			return fallbackInfo, nil
+		}
+		fallbackErrors = append(fallbackErrors, fmt.Sprintf("%v: %v", fallback.name, fallbackErr))
+	}
+	err = fmt.Errorf("%v (fallbacks also failed: %v)", err, strings.Join(fallbackErrors, "; "))
+	return
+}
+
+// windowsRegistryReader reads values out of the CurrentVersion key, keeping
+// track of required values that are missing or can't be parsed. Missing
+// optional values are not an error, and invalid ones are only recorded.
+type windowsRegistryReader struct {
+	values          registryValues
+	missing         []string
+	invalid         []string
+	invalidOptional []string
+}
 
-	return extractRegistryString(id, raw)
+func (r *windowsRegistryReader) readString(id string, required bool) (string, bool) {
+	value, ok := r.values.lookup(id)
+	if !ok {
+		if required {
+			r.missing = append(r.missing, id)
+		}
+		return "", false
+	}
+	return value.data, true
 }
 
-func getRegistryInt(id string) (int, error) {
-	raw, err := getRegistryRaw(id)
+func (r *windowsRegistryReader) readInt(id string, required bool) (int, bool) {
+	stringValue, ok := r.readString(id, required)
+	if !ok {
+		return 0, false
+	}
+	result, err := hexToInt(stringValue)
 	if err != nil {
-		return 0, err
+		problem := fmt.Sprintf("%v (%v)", id, err)
+		if required {
+			r.invalid = append(r.invalid, problem)
+		} else {
+			r.invalidOptional = append(r.invalidOptional, problem)
+		}
+		return 0, false
 	}
+	return result, true
+}
 
-	return extractRegistryInt(id, raw)
+func (r *windowsRegistryReader) requiredString(id string) (string, bool) {
+	return r.readString(id, true)
 }
 
-func getOSInfoWindows() (info *OSInfo, err error) {
-	info = new(OSInfo)
-	populateFromRuntime(info)
-	info.ID = "windows"
+func (r *windowsRegistryReader) optionalString(id string) string {
+	result, _ := r.readString(id, false)
+	return result
+}
 
-	var versionMajor int
-	var versionMinor int
+func (r *windowsRegistryReader) requiredInt(id string) (int, bool) {
+	return r.readInt(id, true)
+}
 
-	// Only Windows 10+ has this
-	versionMinor, err = getRegistryInt("CurrentMinorVersionNumber")
-	versionMajor, err = getRegistryInt("CurrentMajorVersionNumber")
-	if err != nil {
-		err = nil
-		versionMajor = 0
-		versionMinor = 0
-	}
+func (r *windowsRegistryReader) optionalInt(id string) int {
+	result, _ := r.readInt(id, false)
+	return result
+}
 
-	info.Name, err = getRegistryString("ProductName")
-	if err != nil {
-		return
+func (r *windowsRegistryReader) err() error {
+	var problems []string
+	if len(r.missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(r.missing, ", "))
+	}
+	if len(r.invalid) > 0 {
+		problems = append(problems, "invalid "+strings.Join(r.invalid, ", "))
 	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("Error: Could not read Windows version from the registry: %v", strings.Join(problems, "; "))
+}
 
-	var servicePack string
-	servicePack, err = getRegistryString("CSDVersion")
-	if err == nil {
+// populateWindowsInfo fills in info from the values of the CurrentVersion
+// key. Everything that can be read is filled in even if an error is
+// returned, which only happens when a required value is missing or invalid.
+func populateWindowsInfo(info *OSInfo, values registryValues) error {
+	r := &windowsRegistryReader{values: values}
+
+	info.Name, _ = r.requiredString("ProductName")
+	if servicePack := r.optionalString("CSDVersion"); servicePack != "" && info.Name != "" {
 		info.Name = info.Name + " " + servicePack
 	}
 
-	if versionMajor == 0 {
-		info.Version, err = getRegistryString("CurrentVersion")
-		if err != nil {
-			return
+	// Only Windows 10+ has the major and minor version numbers. CurrentVersion
+	// is stuck at 6.3 there, so if either number is present both are required.
+	_, hasMajor := values.lookup("CurrentMajorVersionNumber")
+	_, hasMinor := values.lookup("CurrentMinorVersionNumber")
+	if hasMajor || hasMinor {
+		versionMajor, majorOK := r.requiredInt("CurrentMajorVersionNumber")
+		versionMinor, minorOK := r.requiredInt("CurrentMinorVersionNumber")
+		if majorOK && minorOK {
+			info.Version = fmt.Sprintf("%v.%v", versionMajor, versionMinor)
 		}
 	} else {
-		info.Version = fmt.Sprintf("%v.%v", versionMajor, versionMinor)
+		info.Version, _ = r.requiredString("CurrentVersion")
 	}
 
-	info.Codename, err = getRegistryString("ReleaseID")
-	if err != nil {
-		info.Codename = ""
+	info.Codename = r.optionalString("ReleaseId")
+
+	var hasBuild bool
+	info.Build, hasBuild = r.requiredString("CurrentBuild")
+	readWindowsDetails(info, r)
+	if hasBuild {
+		applyWindowsRelease(info)
 	}
+	return r.err()
+}
 
-	info.Build, err = getRegistryString("CurrentBuild")
-	return
+// These values are all optional, and missing from older Windows versions.
+func readWindowsDetails(info *OSInfo, r *windowsRegistryReader) {
+	details := &info.Windows
+	details.Edition = r.optionalString("EditionID")
+	details.InstallationType = r.optionalString("InstallationType")
+	details.CompositionEdition = r.optionalString("CompositionEditionID")
+	details.DisplayVersion = r.optionalString("DisplayVersion")
+	details.UBR = r.optionalInt("UBR")
+	details.InvalidValues = r.invalidOptional
 }
 
 func getOSInfoLinux() (info *OSInfo, err error) {
-	info = new(OSInfo)
+	info, err = getOSInfoLinuxFromFS(os.DirFS("/"))
 	populateFromRuntime(info)
+	// The kernel is only known for the running system, not for another root
+	var kernelErr error
+	if info.Kernel, kernelErr = readLinuxKernel(os.DirFS("/")); kernelErr != nil {
+		info.Kernel, kernelErr = unameKernel()
+	}
+	if kernelErr == nil {
+		enrichLinuxKernel(os.DirFS("/"), &info.Kernel)
+	}
+	// The container is only known for the running system: another root may
+	// well have a /.dockerenv. Its environment is used when PID 1's can't be
+	// read.
+	info.Container = detectContainer(os.DirFS("/"), os.Getenv("container"))
+	return
+}
 
-	info.IsWSL = checkWSL()
+// Paths are relative to the root of fsys (fs.FS paths have no leading slash).
+func getOSInfoLinuxFromFS(fsys fs.FS) (info *OSInfo, err error) {
+	info = new(OSInfo)
+	info.Family = "linux"
+
+	info.IsWSL = checkWSL(fsys)
 
 	var contents string
-	if contents, err = readTextFile("/etc/os-release"); err == nil {
-		parseEtcOSRelease(info, contents)
+	var loadErr error
+	var parseErr error
+	foundOSRelease := false
+	// /usr/lib/os-release must be used when /etc/os-release is missing
+	for _, path := range []string{"etc/os-release", "usr/lib/os-release"} {
+		if contents, loadErr = readTextFile(fsys, path); loadErr == nil {
+			foundOSRelease = true
+			if e := parseEtcOSRelease(info, contents); e != nil {
+				parseErr = fmt.Errorf("/%v: %v", path, e)
+			}
+			break
+		}
+	}
+
+	if contents, err = readTextFile(fsys, "etc/lsb-release"); err == nil {
+		loadErr = nil
+		if e := parseEtcLSBRelease(info, contents); e != nil && parseErr == nil {
+			parseErr = fmt.Errorf("/etc/lsb-release: %v", e)
+		}
+	}
+
+	// Old or minimal systems only have distribution specific release files
+	if !foundOSRelease {
+		found, e := parseLegacyReleaseFiles(fsys, info)
+		if found {
+			loadErr = nil
+		} else if e != nil && parseErr == nil {
+			parseErr = e
+		}
 	}
 
-	lastError := err
+	enrichPointVersion(fsys, info)
+	info.DistroFamily = classifyDistroFamily(info.ID, info.OSRelease.IDLike)
+	info.PackageManager, info.PackageFormat = detectPackageManager(fsys)
 
-	if contents, err = readTextFile("/etc/lsb-release"); err == nil {
-		parseEtcLSBRelease(info, contents)
+	if e := readExtensionReleases(fsys, info); e != nil && parseErr == nil {
+		parseErr = e
 	}
 
-	// Only propagate an error if both files failed to load
-	if lastError == nil {
-		err = nil
+	// Only propagate a load error if no release file could be loaded
+	err = loadErr
+	if err == nil {
+		err = parseErr
 	}
 
 	return
@@ -303,6 +591,8 @@ func getOSInfoFreeBSD() (info *OSInfo, err error) {
 	info = new(OSInfo)
 	populateFromRuntime(info)
 	info.ID = "freebsd"
+	info.PackageManager, info.PackageFormat = detectPackageManager(os.DirFS("/"))
+	info.Kernel, _ = readUnameKernel(true)
 
 	var contents string
 	contents, err = readCommandOutput("/usr/bin/uname", "-v")
@@ -314,10 +604,13 @@ func getOSInfoFreeBSD() (info *OSInfo, err error) {
 }
 
 func getOSInfoMac() (info *OSInfo, err error) {
-	info = new(OSInfo)
+	// SystemVersion.plist saves spawning sw_vers
+	info, err = getOSInfoMacFromFS(os.DirFS("/"))
 	populateFromRuntime(info)
-	info.ID = "darwin"
-	info.Name = "Mac OS X"
+	info.Kernel, _ = readUnameKernel(false)
+	if err == nil {
+		return
+	}
 
 	var productVersion string
 	productVersion, err = readCommandOutput("/usr/bin/sw_vers", "-productVersion")
@@ -334,6 +627,22 @@ func getOSInfoMac() (info *OSInfo, err error) {
 	return
 }
 
+const macSystemVersionPath = "System/Library/CoreServices/SystemVersion.plist"
+
+func getOSInfoMacFromFS(fsys fs.FS) (info *OSInfo, err error) {
+	info = new(OSInfo)
+	info.Family = "darwin"
+	info.ID = "darwin"
+	info.Name = "macOS"
+	info.PackageManager, info.PackageFormat = detectPackageManager(fsys)
+
+	var contents []byte
+	if contents, err = fs.ReadFile(fsys, macSystemVersionPath); err == nil {
+		err = parseMacSystemVersion(info, contents)
+	}
+	return
+}
+
 func getOSInfoUnknown() (info *OSInfo, err error) {
 	info = new(OSInfo)
 	populateFromRuntime(info)
@@ -353,8 +662,8 @@ func getOSInfoUnknown() (info *OSInfo, err error) {
 	return
 }
 
-func checkWSL() bool {
-	contents, err := readTextFile("/proc/version")
+func checkWSL(fsys fs.FS) bool {
+	contents, err := readTextFile(fsys, "proc/version")
 	if err != nil {
 		return false
 	}
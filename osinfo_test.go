@@ -2,7 +2,10 @@ package osinfo
 
 import (
 	"fmt"
+	"io/ioutil"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func expectEqualStrings(t *testing.T, expected, actual string) {
@@ -17,8 +20,16 @@ func expectEqualInts(t *testing.T, expected, actual int) {
 	}
 }
 
-func TestAlpine(t *testing.T) {
-	osRelease := `NAME="Alpine Linux"
+// readFixture reads a file from testdata
+func readFixture(t testing.TB, name string) []byte {
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+const alpineOSRelease = `NAME="Alpine Linux"
 ID=alpine
 VERSION_ID=3.8.0
 PRETTY_NAME="Alpine Linux v3.8"
@@ -26,18 +37,7 @@ HOME_URL="http://alpinelinux.org"
 BUG_REPORT_URL="http://bugs.alpinelinux.org"
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	// Alpine has no /etc/lsb-release
-
-	expectEqualStrings(t, "alpine", info.ID)
-	expectEqualStrings(t, "3.8.0", info.Version)
-	expectEqualStrings(t, "Alpine Linux", info.Name)
-	expectEqualStrings(t, "", info.Codename)
-}
-
-func TestCentos(t *testing.T) {
-	osRelease := `NAME="CentOS Linux"
+const centosOSRelease = `NAME="CentOS Linux"
 VERSION="8 (Core)"
 ID="centos"
 ID_LIKE="rhel fedora"
@@ -56,18 +56,7 @@ REDHAT_SUPPORT_PRODUCT_VERSION="8"
 
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	// CentOS has no /etc/lsb-release
-
-	expectEqualStrings(t, "centos", info.ID)
-	expectEqualStrings(t, "8", info.Version)
-	expectEqualStrings(t, "CentOS Linux", info.Name)
-	expectEqualStrings(t, "", info.Codename)
-}
-
-func TestDebian(t *testing.T) {
-	osRelease := `PRETTY_NAME="Debian GNU/Linux 9 (stretch)"
+const debianOSRelease = `PRETTY_NAME="Debian GNU/Linux 9 (stretch)"
 NAME="Debian GNU/Linux"
 VERSION_ID="9"
 VERSION="9 (stretch)"
@@ -78,18 +67,7 @@ SUPPORT_URL="https://www.debian.org/support"
 BUG_REPORT_URL="https://bugs.debian.org/"
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	// Debian has no /etc/lsb-release
-
-	expectEqualStrings(t, "debian", info.ID)
-	expectEqualStrings(t, "9", info.Version)
-	expectEqualStrings(t, "Debian GNU/Linux", info.Name)
-	expectEqualStrings(t, "stretch", info.Codename)
-}
-
-func TestFedora(t *testing.T) {
-	osRelease := `NAME=Fedora
+const fedoraOSRelease = `NAME=Fedora
 VERSION="31 (Container Image)"
 ID=fedora
 VERSION_ID=31
@@ -112,18 +90,7 @@ VARIANT="Container Image"
 VARIANT_ID=container
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	// Fedora has no /etc/lsb-release
-
-	expectEqualStrings(t, "fedora", info.ID)
-	expectEqualStrings(t, "31", info.Version)
-	expectEqualStrings(t, "Fedora", info.Name)
-	expectEqualStrings(t, "", info.Codename)
-}
-
-func TestGentoo(t *testing.T) {
-	osRelease := `NAME=Gentoo
+const gentooOSRelease = `NAME=Gentoo
 ID=gentoo
 PRETTY_NAME="Gentoo/Linux"
 ANSI_COLOR="1;32"
@@ -132,18 +99,7 @@ SUPPORT_URL="https://www.gentoo.org/support/"
 BUG_REPORT_URL="https://bugs.gentoo.org/"
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	// Gentoo has no /etc/lsb-release
-
-	expectEqualStrings(t, "gentoo", info.ID)
-	expectEqualStrings(t, "", info.Version)
-	expectEqualStrings(t, "Gentoo", info.Name)
-	expectEqualStrings(t, "", info.Codename)
-}
-
-func TestKali(t *testing.T) {
-	osRelease := `PRETTY_NAME="Kali GNU/Linux Rolling"
+const kaliOSRelease = `PRETTY_NAME="Kali GNU/Linux Rolling"
 NAME="Kali GNU/Linux"
 ID=kali
 VERSION="2020.2"
@@ -156,18 +112,7 @@ SUPPORT_URL="https://forums.kali.org/"
 BUG_REPORT_URL="https://bugs.kali.org/"
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	// Kali has no /etc/lsb-release
-
-	expectEqualStrings(t, "kali", info.ID)
-	expectEqualStrings(t, "2020.2", info.Version)
-	expectEqualStrings(t, "Kali GNU/Linux", info.Name)
-	expectEqualStrings(t, "kali-rolling", info.Codename)
-}
-
-func TestManjaro(t *testing.T) {
-	osRelease := `NAME="Manjaro Linux"
+const manjaroOSRelease = `NAME="Manjaro Linux"
 ID=manjaro
 ID_LIKE=arch
 PRETTY_NAME="Manjaro Linux"
@@ -177,24 +122,14 @@ SUPPORT_URL="https://www.manjaro.org/"
 BUG_REPORT_URL="https://bugs.manjaro.org/"
 LOGO=manjarolinux
 `
-	lsbRelease := `DISTRIB_ID=ManjaroLinux
+
+const manjaroLSBRelease = `DISTRIB_ID=ManjaroLinux
 DISTRIB_RELEASE=19.0.2
 DISTRIB_CODENAME=Kyria
 DISTRIB_DESCRIPTION="Manjaro Linux"
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	parseEtcLSBRelease(info, lsbRelease)
-
-	expectEqualStrings(t, "manjaro", info.ID)
-	expectEqualStrings(t, "19.0.2", info.Version)
-	expectEqualStrings(t, "Manjaro Linux", info.Name)
-	expectEqualStrings(t, "Kyria", info.Codename)
-}
-
-func TestOpenSUSE(t *testing.T) {
-	osRelease := `NAME="openSUSE Leap"
+const openSUSEOSRelease = `NAME="openSUSE Leap"
 VERSION="15.1"
 ID="opensuse-leap"
 ID_LIKE="suse opensuse"
@@ -206,18 +141,7 @@ BUG_REPORT_URL="https://bugs.opensuse.org"
 HOME_URL="https://www.opensuse.org/"
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	// OpenSUSE has no /etc/lsb-release
-
-	expectEqualStrings(t, "opensuse-leap", info.ID)
-	expectEqualStrings(t, "15.1", info.Version)
-	expectEqualStrings(t, "openSUSE Leap", info.Name)
-	expectEqualStrings(t, "", info.Codename)
-}
-
-func TestOracle(t *testing.T) {
-	osRelease := `NAME="Oracle Linux Server"
+const oracleOSRelease = `NAME="Oracle Linux Server"
 VERSION="8.1"
 ID="ol"
 ID_LIKE="fedora"
@@ -237,18 +161,7 @@ ORACLE_SUPPORT_PRODUCT="Oracle Linux"
 ORACLE_SUPPORT_PRODUCT_VERSION=8.1
 `
 
-	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	// Oracle has no /etc/lsb-release
-
-	expectEqualStrings(t, "ol", info.ID)
-	expectEqualStrings(t, "8.1", info.Version)
-	expectEqualStrings(t, "Oracle Linux Server", info.Name)
-	expectEqualStrings(t, "", info.Codename)
-}
-
-func TestUbuntu(t *testing.T) {
-	osRelease := `NAME="Ubuntu"
+const ubuntuOSRelease = `NAME="Ubuntu"
 VERSION="19.10 (Eoan Ermine)"
 ID=ubuntu
 ID_LIKE=debian
@@ -261,20 +174,139 @@ PRIVACY_POLICY_URL="https://www.ubuntu.com/legal/terms-and-policies/privacy-poli
 VERSION_CODENAME=eoan
 UBUNTU_CODENAME=eoan
 `
-	lsbRelease := `DISTRIB_ID=Ubuntu
+
+const ubuntuLSBRelease = `DISTRIB_ID=Ubuntu
 DISTRIB_RELEASE=19.10
 DISTRIB_CODENAME=eoan
 DISTRIB_DESCRIPTION="Ubuntu 19.10"
 `
 
+func TestAlpine(t *testing.T) {
 	info := new(OSInfo)
-	parseEtcOSRelease(info, osRelease)
-	parseEtcLSBRelease(info, lsbRelease)
+	parseEtcOSRelease(info, alpineOSRelease)
+	// Alpine has no /etc/lsb-release
+
+	expectEqualStrings(t, "alpine", info.ID)
+	expectEqualStrings(t, "3.8.0", info.Version)
+	expectEqualStrings(t, "Alpine Linux", info.Name)
+	expectEqualStrings(t, "", info.Codename)
+}
+
+func TestCentos(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, centosOSRelease)
+	// CentOS has no /etc/lsb-release
+
+	expectEqualStrings(t, "centos", info.ID)
+	expectEqualStrings(t, "8", info.Version)
+	expectEqualStrings(t, "CentOS Linux", info.Name)
+	expectEqualStrings(t, "", info.Codename)
+
+	expectEqualStrings(t, "CentOS Linux 8 (Core)", info.OSRelease.PrettyName)
+	expectEqualStrings(t, "8 (Core)", info.OSRelease.Version)
+	expectEqualStrings(t, "cpe:/o:centos:centos:8", info.OSRelease.CPEName)
+	expectEqualStrings(t, "https://www.centos.org/", info.OSRelease.HomeURL)
+	expectEqualStrings(t, "CentOS-8", info.OSRelease.Fields["CENTOS_MANTISBT_PROJECT"])
+	expectEqualInts(t, 2, len(info.OSRelease.IDLike))
+	expectEqualStrings(t, "rhel", info.OSRelease.IDLike[0])
+	expectEqualStrings(t, "fedora", info.OSRelease.IDLike[1])
+}
+
+func TestDebian(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, debianOSRelease)
+	// Debian has no /etc/lsb-release
+
+	expectEqualStrings(t, "debian", info.ID)
+	expectEqualStrings(t, "9", info.Version)
+	expectEqualStrings(t, "Debian GNU/Linux", info.Name)
+	expectEqualStrings(t, "stretch", info.Codename)
+}
+
+func TestFedora(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, fedoraOSRelease)
+	// Fedora has no /etc/lsb-release
+
+	expectEqualStrings(t, "fedora", info.ID)
+	expectEqualStrings(t, "31", info.Version)
+	expectEqualStrings(t, "Fedora", info.Name)
+	expectEqualStrings(t, "", info.Codename)
+
+	expectEqualStrings(t, "Container Image", info.OSRelease.Variant)
+	expectEqualStrings(t, "container", info.OSRelease.VariantID)
+	expectEqualStrings(t, "fedora-logo-icon", info.OSRelease.Logo)
+	expectEqualInts(t, 0, len(info.OSRelease.IDLike))
+}
+
+func TestGentoo(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, gentooOSRelease)
+	// Gentoo has no /etc/lsb-release
+
+	expectEqualStrings(t, "gentoo", info.ID)
+	expectEqualStrings(t, "", info.Version)
+	expectEqualStrings(t, "Gentoo", info.Name)
+	expectEqualStrings(t, "", info.Codename)
+}
+
+func TestKali(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, kaliOSRelease)
+	// Kali has no /etc/lsb-release
+
+	expectEqualStrings(t, "kali", info.ID)
+	expectEqualStrings(t, "2020.2", info.Version)
+	expectEqualStrings(t, "Kali GNU/Linux", info.Name)
+	expectEqualStrings(t, "kali-rolling", info.Codename)
+}
+
+func TestManjaro(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, manjaroOSRelease)
+	parseEtcLSBRelease(info, manjaroLSBRelease)
+
+	expectEqualStrings(t, "manjaro", info.ID)
+	expectEqualStrings(t, "19.0.2", info.Version)
+	expectEqualStrings(t, "Manjaro Linux", info.Name)
+	expectEqualStrings(t, "Kyria", info.Codename)
+}
+
+func TestOpenSUSE(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, openSUSEOSRelease)
+	// OpenSUSE has no /etc/lsb-release
+
+	expectEqualStrings(t, "opensuse-leap", info.ID)
+	expectEqualStrings(t, "15.1", info.Version)
+	expectEqualStrings(t, "openSUSE Leap", info.Name)
+	expectEqualStrings(t, "", info.Codename)
+}
+
+func TestOracle(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, oracleOSRelease)
+	// Oracle has no /etc/lsb-release
+
+	expectEqualStrings(t, "ol", info.ID)
+	expectEqualStrings(t, "8.1", info.Version)
+	expectEqualStrings(t, "Oracle Linux Server", info.Name)
+	expectEqualStrings(t, "", info.Codename)
+}
+
+func TestUbuntu(t *testing.T) {
+	info := new(OSInfo)
+	parseEtcOSRelease(info, ubuntuOSRelease)
+	parseEtcLSBRelease(info, ubuntuLSBRelease)
 
 	expectEqualStrings(t, "ubuntu", info.ID)
 	expectEqualStrings(t, "19.10", info.Version)
 	expectEqualStrings(t, "Ubuntu", info.Name)
 	expectEqualStrings(t, "eoan", info.Codename)
+
+	expectEqualStrings(t, "Ubuntu 19.10", info.OSRelease.PrettyName)
+	expectEqualStrings(t, "eoan", info.OSRelease.UbuntuCodename)
+	expectEqualStrings(t, "debian", info.OSRelease.IDLike[0])
 }
 
 func TestMacOSSierra(t *testing.T) {
@@ -289,6 +321,37 @@ func TestMacOSSierra(t *testing.T) {
 	expectEqualStrings(t, "10.12.6", info.Version)
 	expectEqualStrings(t, "Sierra", info.Codename)
 	expectEqualStrings(t, "16G1815", info.Build)
+	expectEqualStrings(t, "macOS", info.Name)
+}
+
+func expectMacRelease(t *testing.T, productVersion, name, codename string) {
+	info := new(OSInfo)
+	if err := parseMacSWVers(info, productVersion, "build"); err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, name, info.Name)
+	expectEqualStrings(t, codename, info.Codename)
+}
+
+func TestMacOSReleases(t *testing.T) {
+	expectMacRelease(t, "10.6.8", "Mac OS X", "Snow Leopard")
+	expectMacRelease(t, "10.9.5", "OS X", "Mavericks")
+	expectMacRelease(t, "10.11.6", "OS X", "El Capitan")
+	expectMacRelease(t, "10.15.7", "macOS", "Catalina")
+	expectMacRelease(t, "10.16", "macOS", "Big Sur")
+	expectMacRelease(t, "11.7.10", "macOS", "Big Sur")
+	expectMacRelease(t, "12.7.4", "macOS", "Monterey")
+	expectMacRelease(t, "14.5", "macOS", "Sonoma")
+	expectMacRelease(t, "15.0.1", "macOS", "Sequoia")
+	expectMacRelease(t, "26.0", "macOS", "Tahoe")
+	expectMacRelease(t, "99.1", "macOS", "unknown")
+}
+
+func TestMacOSBadVersion(t *testing.T) {
+	info := new(OSInfo)
+	if err := parseMacSWVers(info, "garbage", "build"); err == nil {
+		t.Error("Expected an error for an unparseable product version")
+	}
 }
 
 func TestFreeBSD(t *testing.T) {
@@ -305,7 +368,7 @@ func TestFreeBSD(t *testing.T) {
 }
 
 func expectRegistryString(t *testing.T, expected string, id string, regOutput string) {
-	result, err := extractRegistryString(id, regOutput)
+	result, err := parseRegistryValues(regOutput).getString(id)
 	if err != nil {
 		t.Error(err)
 	}
@@ -313,7 +376,7 @@ func expectRegistryString(t *testing.T, expected string, id string, regOutput st
 }
 
 func expectRegistryInt(t *testing.T, expected int, id string, regOutput string) {
-	result, err := extractRegistryInt(id, regOutput)
+	result, err := parseRegistryValues(regOutput).getInt(id)
 	if err != nil {
 		t.Error(err)
 	}
@@ -355,6 +418,213 @@ HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
 `)
 }
 
+func TestWindowsRegistryValuesWindows11(t *testing.T) {
+	values := parseRegistryValues(string(readFixture(t, "reg_query_windows11_23h2.txt")))
+	expectEqualInts(t, 31, len(values))
+
+	expectRegistryValue := func(expected, id string) {
+		result, err := values.getString(id)
+		if err != nil {
+			t.Error(err)
+		}
+		expectEqualStrings(t, expected, result)
+	}
+	expectRegistryValue("Windows 10 Pro", "ProductName")
+	expectRegistryValue("22631", "CurrentBuild")
+	expectRegistryValue("23H2", "DisplayVersion")
+	expectRegistryValue("2009", "ReleaseID")
+	expectRegistryValue("C:\\WINDOWS", "SystemRoot")
+	expectRegistryValue("", "RegisteredOrganization")
+	expectRegistryValue("0x1d9f0a1b2c3d4e5", "InstallTime")
+
+	major, err := values.getInt("CurrentMajorVersionNumber")
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualInts(t, 10, major)
+	ubr, err := values.getInt("UBR")
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualInts(t, 3447, ubr)
+
+	if _, err := values.getString("CSDVersion"); err == nil {
+		t.Errorf("Expected CSDVersion to be missing")
+	}
+	if _, err := values.getString("Winlogon"); err == nil {
+		t.Errorf("Expected subkeys not to be parsed as values")
+	}
+}
+
+func TestWindowsRegistryValuesWindows7(t *testing.T) {
+	values := parseRegistryValues(string(readFixture(t, "reg_query_windows7_sp1.txt")))
+	expectEqualInts(t, 20, len(values))
+
+	productName, _ := values.getString("ProductName")
+	expectEqualStrings(t, "Windows 7 Professional", productName)
+	servicePack, _ := values.getString("CSDVersion")
+	expectEqualStrings(t, "Service Pack 1", servicePack)
+	if _, err := values.getInt("CurrentMajorVersionNumber"); err == nil {
+		t.Errorf("Expected CurrentMajorVersionNumber to be missing")
+	}
+}
+
+func TestWindowsExtractFromFullKey(t *testing.T) {
+	output := string(readFixture(t, "reg_query_windows11_23h2.txt"))
+	expectRegistryString(t, "Windows 10 Pro", "ProductName", output)
+	expectRegistryString(t, "6.3", "CurrentVersion", output)
+	expectRegistryInt(t, 0, "CurrentMinorVersionNumber", output)
+	expectRegistryInt(t, 10, "CurrentMajorVersionNumber", output)
+	// CurrentBuild must not match CurrentBuildNumber or BaseBuildRevisionNumber
+	expectRegistryString(t, "22631", "CurrentBuild", output)
+}
+
+// Drops the named values from recorded reg.exe output
+func withoutRegistryValues(output string, ids ...string) string {
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		drop := false
+		for _, id := range ids {
+			if strings.HasPrefix(line, "    "+id+"    ") {
+				drop = true
+			}
+		}
+		if !drop {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func windowsInfoFromFixture(t *testing.T, output string) (*OSInfo, error) {
+	info := &OSInfo{ID: "windows"}
+	err := populateWindowsInfo(info, parseRegistryValues(output))
+	return info, err
+}
+
+func expectWindowsError(t *testing.T, expected string, err error) {
+	if err == nil {
+		t.Errorf("Expected error [%v] but got none", expected)
+		return
+	}
+	expectEqualStrings(t, "Error: Could not read Windows version from the registry: "+expected, err.Error())
+}
+
+func TestPopulateWindows11(t *testing.T) {
+	info, err := windowsInfoFromFixture(t, string(readFixture(t, "reg_query_windows11_23h2.txt")))
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+	expectEqualStrings(t, "10.0", info.Version)
+	expectEqualStrings(t, "22631", info.Build)
+	expectEqualStrings(t, "23H2", info.Codename)
+	expectEqualStrings(t, "10.0.22631.3447", info.Windows.FullBuild)
+}
+
+func TestPopulateWindows7(t *testing.T) {
+	info, err := windowsInfoFromFixture(t, string(readFixture(t, "reg_query_windows7_sp1.txt")))
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "Windows 7 Professional Service Pack 1", info.Name)
+	expectEqualStrings(t, "6.1", info.Version)
+	expectEqualStrings(t, "7601", info.Build)
+	expectEqualStrings(t, "", info.Codename)
+	expectEqualInts(t, 0, info.Windows.UBR)
+}
+
+func TestPopulateWindowsMissingOptional(t *testing.T) {
+	output := withoutRegistryValues(string(readFixture(t, "reg_query_windows11_23h2.txt")),
+		"ReleaseId", "DisplayVersion", "EditionID", "InstallationType", "CompositionEditionID", "UBR")
+	info, err := windowsInfoFromFixture(t, output)
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+	// Falls back to the display version known for the build
+	expectEqualStrings(t, "23H2", info.Codename)
+	expectEqualStrings(t, "10.0.22631", info.Windows.FullBuild)
+}
+
+func TestPopulateWindowsMissingRequired(t *testing.T) {
+	win11 := string(readFixture(t, "reg_query_windows11_23h2.txt"))
+	win7 := string(readFixture(t, "reg_query_windows7_sp1.txt"))
+
+	_, err := windowsInfoFromFixture(t, withoutRegistryValues(win11, "ProductName"))
+	expectWindowsError(t, "missing ProductName", err)
+
+	info, err := windowsInfoFromFixture(t, withoutRegistryValues(win11, "CurrentBuild"))
+	expectWindowsError(t, "missing CurrentBuild", err)
+	expectEqualStrings(t, "10.0", info.Version)
+
+	info, err = windowsInfoFromFixture(t, withoutRegistryValues(win11, "CurrentMinorVersionNumber"))
+	expectWindowsError(t, "missing CurrentMinorVersionNumber", err)
+	expectEqualStrings(t, "", info.Version)
+
+	_, err = windowsInfoFromFixture(t, withoutRegistryValues(win11, "CurrentMajorVersionNumber"))
+	expectWindowsError(t, "missing CurrentMajorVersionNumber", err)
+
+	// Without the version numbers, CurrentVersion is used
+	info, err = windowsInfoFromFixture(t, withoutRegistryValues(win11, "CurrentMajorVersionNumber", "CurrentMinorVersionNumber"))
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "6.3", info.Version)
+
+	_, err = windowsInfoFromFixture(t, withoutRegistryValues(win7, "CurrentVersion"))
+	expectWindowsError(t, "missing CurrentVersion", err)
+
+	_, err = windowsInfoFromFixture(t, withoutRegistryValues(win7, "ProductName", "CurrentVersion", "CurrentBuild"))
+	expectWindowsError(t, "missing ProductName, CurrentVersion, CurrentBuild", err)
+
+	_, err = windowsInfoFromFixture(t, "")
+	expectWindowsError(t, "missing ProductName, CurrentVersion, CurrentBuild", err)
+}
+
+func TestPopulateWindowsInvalidValues(t *testing.T) {
+	win11 := string(readFixture(t, "reg_query_windows11_23h2.txt"))
+
+	info, err := windowsInfoFromFixture(t, strings.Replace(win11, "REG_DWORD    0xa", "REG_DWORD    10", 1))
+	expectWindowsError(t, "invalid CurrentMajorVersionNumber (10: Not a hex number)", err)
+	expectEqualStrings(t, "", info.Version)
+	expectEqualStrings(t, "22631", info.Build)
+
+	// Invalid optional values are recorded, but the rest is still good
+	info, err = windowsInfoFromFixture(t, strings.Replace(win11, "0xd77", "0xzz", 1))
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+	expectEqualStrings(t, "10.0.22631", info.Windows.FullBuild)
+	expectEqualStrings(t, "UBR (encoding/hex: invalid byte: U+007A 'z')", strings.Join(info.Windows.InvalidValues, "; "))
+
+	info, err = windowsInfoFromFixture(t, strings.Replace(withoutRegistryValues(win11, "ProductName"), "0xd77", "d77", 1))
+	expectWindowsError(t, "missing ProductName", err)
+	expectEqualStrings(t, "UBR (d77: Not a hex number)", strings.Join(info.Windows.InvalidValues, "; "))
+}
+
+func BenchmarkParseRegistryValues(b *testing.B) {
+	output := string(readFixture(b, "reg_query_windows11_23h2.txt"))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parseRegistryValues(output)
+	}
+}
+
+func BenchmarkRegistryLookups(b *testing.B) {
+	values := parseRegistryValues(string(readFixture(b, "reg_query_windows11_23h2.txt")))
+	ids := []string{"CurrentMinorVersionNumber", "CurrentMajorVersionNumber", "ProductName",
+		"CSDVersion", "CurrentVersion", "ReleaseId", "CurrentBuild"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, id := range ids {
+			values.getString(id)
+		}
+	}
+}
+
 func TestDemonstrate(t *testing.T) {
 	info, err := GetOSInfo()
 	if err != nil {
@@ -396,3 +666,45 @@ UBUNTU_CODENAME=focal`
 		t.Error("Expected IsWSL to be true")
 	}
 }
+
+func TestGetOSInfoFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/os-release": &fstest.MapFile{Data: []byte(`NAME="Ubuntu"
+VERSION="20.04 LTS (Focal Fossa)"
+ID=ubuntu
+ID_LIKE=debian
+VERSION_ID="20.04"
+VERSION_CODENAME=focal
+`)},
+		"etc/lsb-release": &fstest.MapFile{Data: []byte(`DISTRIB_ID=Ubuntu
+DISTRIB_RELEASE=20.04
+DISTRIB_CODENAME=focal
+DISTRIB_DESCRIPTION="Ubuntu 20.04 LTS"
+`)},
+		"proc/version": &fstest.MapFile{Data: []byte("Linux version 4.4.0-19041-Microsoft (Microsoft@Microsoft.com) (gcc version 5.4.0 (GCC) ) #488-Microsoft Mon Sep 01 13:43:00 PST 2020\n")},
+	}
+
+	info, err := GetOSInfoFromFS(fsys)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectEqualStrings(t, "linux", info.Family)
+	expectEqualStrings(t, "", info.Architecture)
+	expectEqualStrings(t, "ubuntu", info.ID)
+	expectEqualStrings(t, "20.04", info.Version)
+	expectEqualStrings(t, "Ubuntu (WSL)", info.Name)
+	expectEqualStrings(t, "focal", info.Codename)
+	if !info.IsWSL {
+		t.Error("Expected IsWSL to be true")
+	}
+}
+
+func TestGetOSInfoFromEmptyFS(t *testing.T) {
+	info, err := GetOSInfoFromFS(fstest.MapFS{})
+	if err == nil {
+		t.Error("Expected an error for a filesystem without release files")
+	}
+	expectEqualStrings(t, "linux", info.Family)
+	expectEqualStrings(t, "", info.ID)
+}
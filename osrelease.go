@@ -0,0 +1,261 @@
+package osinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// OSRelease holds the contents of an os-release file, as described in
+// https://www.freedesktop.org/software/systemd/man/os-release.html
+// Fields that are absent from the file are left empty.
+type OSRelease struct {
+	Name             string
+	ID               string
+	IDLike           []string
+	PrettyName       string
+	CPEName          string
+	Variant          string
+	VariantID        string
+	Version          string
+	VersionID        string
+	VersionCodename  string
+	BuildID          string
+	ImageID          string
+	ImageVersion     string
+	HomeURL          string
+	DocumentationURL string
+	SupportURL       string
+	BugReportURL     string
+	PrivacyPolicyURL string
+	SupportEnd       string
+	Logo             string
+	ANSIColor        string
+	VendorName       string
+	VendorURL        string
+	DefaultHostname  string
+	Architecture     string
+	// Ubuntu only
+	UbuntuCodename string
+
+	// Every key/value pair in the file, including the ones above and any
+	// vendor extensions (such as REDHAT_SUPPORT_PRODUCT).
+	Fields map[string]string
+}
+
+func newOSRelease(keyvalues map[string]string) OSRelease {
+	return OSRelease{
+		Name:             keyvalues["NAME"],
+		ID:               keyvalues["ID"],
+		IDLike:           strings.Fields(keyvalues["ID_LIKE"]),
+		PrettyName:       keyvalues["PRETTY_NAME"],
+		CPEName:          keyvalues["CPE_NAME"],
+		Variant:          keyvalues["VARIANT"],
+		VariantID:        keyvalues["VARIANT_ID"],
+		Version:          keyvalues["VERSION"],
+		VersionID:        keyvalues["VERSION_ID"],
+		VersionCodename:  keyvalues["VERSION_CODENAME"],
+		BuildID:          keyvalues["BUILD_ID"],
+		ImageID:          keyvalues["IMAGE_ID"],
+		ImageVersion:     keyvalues["IMAGE_VERSION"],
+		HomeURL:          keyvalues["HOME_URL"],
+		DocumentationURL: keyvalues["DOCUMENTATION_URL"],
+		SupportURL:       keyvalues["SUPPORT_URL"],
+		BugReportURL:     keyvalues["BUG_REPORT_URL"],
+		PrivacyPolicyURL: keyvalues["PRIVACY_POLICY_URL"],
+		SupportEnd:       keyvalues["SUPPORT_END"],
+		Logo:             keyvalues["LOGO"],
+		ANSIColor:        keyvalues["ANSI_COLOR"],
+		VendorName:       keyvalues["VENDOR_NAME"],
+		VendorURL:        keyvalues["VENDOR_URL"],
+		DefaultHostname:  keyvalues["DEFAULT_HOSTNAME"],
+		Architecture:     keyvalues["ARCHITECTURE"],
+		UbuntuCodename:   keyvalues["UBUNTU_CODENAME"],
+		Fields:           keyvalues,
+	}
+}
+
+// Extension describes a system extension (systemd-sysext) or configuration
+// extension (systemd-confext) merged into the OS, as described by its
+// extension-release file.
+type Extension struct {
+	// The extension's name, taken from its extension-release.NAME file
+	Name string
+	// "sysext" or "confext"
+	Type string
+	// The OS the extension is built for ("_any" if it is OS independent)
+	ID        string
+	VersionID string
+	// The extension's own identity (SYSEXT_ID/CONFEXT_ID etc.)
+	ExtensionID        string
+	ExtensionVersionID string
+	Level              string
+	Scope              string
+	Architecture       string
+
+	// Every key/value pair in the extension-release file
+	Fields map[string]string
+}
+
+var extensionReleaseDirs = []struct {
+	path          string
+	extensionType string
+}{
+	{"usr/lib/extension-release.d", "sysext"},
+	{"etc/extension-release.d", "confext"},
+}
+
+func parseExtensionRelease(name, extensionType, contents string) (Extension, error) {
+	keyvalues, err := parseShellAssignments(contents)
+	prefix := strings.ToUpper(extensionType) + "_"
+	return Extension{
+		Name:               name,
+		Type:               extensionType,
+		ID:                 keyvalues["ID"],
+		VersionID:          keyvalues["VERSION_ID"],
+		ExtensionID:        keyvalues[prefix+"ID"],
+		ExtensionVersionID: keyvalues[prefix+"VERSION_ID"],
+		Level:              keyvalues[prefix+"LEVEL"],
+		Scope:              keyvalues[prefix+"SCOPE"],
+		Architecture:       keyvalues["ARCHITECTURE"],
+		Fields:             keyvalues,
+	}, err
+}
+
+// readExtensionReleases fills info.Extensions from the extension-release
+// files of every merged extension. Missing directories are not an error.
+func readExtensionReleases(fsys fs.FS, info *OSInfo) (err error) {
+	for _, dir := range extensionReleaseDirs {
+		entries, readErr := fs.ReadDir(fsys, dir.path)
+		if readErr != nil {
+			if !errors.Is(readErr, fs.ErrNotExist) && err == nil {
+				err = readErr
+			}
+			continue
+		}
+		for _, entry := range entries {
+			name := strings.TrimPrefix(entry.Name(), "extension-release.")
+			if name == entry.Name() || name == "" {
+				continue
+			}
+			filePath := path.Join(dir.path, entry.Name())
+			contents, readErr := readTextFile(fsys, filePath)
+			if readErr != nil {
+				if err == nil {
+					err = readErr
+				}
+				continue
+			}
+			extension, parseErr := parseExtensionRelease(name, dir.extensionType, contents)
+			if parseErr != nil && err == nil {
+				err = fmt.Errorf("/%v: %v", filePath, parseErr)
+			}
+			info.Extensions = append(info.Extensions, extension)
+		}
+	}
+	return
+}
+
+// parseShellAssignments parses the VAR=VALUE lines of an os-release style
+// file, following the shell-compatible quoting rules of the os-release spec.
+// Malformed lines are skipped and reported (with their line numbers) in err,
+// while every well-formed line is still returned in kvmap.
+func parseShellAssignments(contents string) (kvmap map[string]string, err error) {
+	kvmap = make(map[string]string)
+	var problems []string
+	for i, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		separator := strings.IndexByte(line, '=')
+		if separator < 0 {
+			problems = append(problems, fmt.Sprintf("line %v: missing '='", i+1))
+			continue
+		}
+		key := line[:separator]
+		if !isShellVariableName(key) {
+			problems = append(problems, fmt.Sprintf("line %v: invalid variable name [%v]", i+1, key))
+			continue
+		}
+		value, parseErr := unquoteShellValue(line[separator+1:])
+		if parseErr != nil {
+			problems = append(problems, fmt.Sprintf("line %v: %v", i+1, parseErr))
+			continue
+		}
+		kvmap[key] = value
+	}
+
+	if len(problems) > 0 {
+		err = fmt.Errorf("%v", strings.Join(problems, "; "))
+	}
+	return
+}
+
+func isShellVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// unquoteShellValue decodes a value the way a shell would, without performing
+// any variable or command expansion (which the spec forbids).
+func unquoteShellValue(raw string) (string, error) {
+	var value strings.Builder
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch c {
+		case '\'':
+			end := strings.IndexByte(raw[i+1:], '\'')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated single quote")
+			}
+			value.WriteString(raw[i+1 : i+1+end])
+			i += end + 1
+		case '"':
+			for i++; i < len(raw) && raw[i] != '"'; i++ {
+				switch raw[i] {
+				case '\\':
+					if i+1 < len(raw) && strings.IndexByte("$`\"\\", raw[i+1]) >= 0 {
+						i++
+					}
+				case '$', '`':
+					return "", fmt.Errorf("unescaped %q in double quotes", raw[i])
+				}
+				value.WriteByte(raw[i])
+			}
+			if i >= len(raw) {
+				return "", fmt.Errorf("unterminated double quote")
+			}
+		case '\\':
+			if i+1 >= len(raw) {
+				return "", fmt.Errorf("trailing backslash")
+			}
+			i++
+			value.WriteByte(raw[i])
+		case ' ', '\t':
+			// Whitespace can only be followed by a comment
+			if rest := strings.TrimLeft(raw[i:], " \t"); rest != "" && rest[0] != '#' {
+				return "", fmt.Errorf("unquoted whitespace in value")
+			}
+			return value.String(), nil
+		case '$', '`', ';', '&', '|', '<', '>', '(', ')':
+			return "", fmt.Errorf("unquoted %q in value", c)
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return value.String(), nil
+}
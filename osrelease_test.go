@@ -0,0 +1,187 @@
+package osinfo
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func expectShellValue(t *testing.T, expected string, raw string) {
+	kvmap, err := parseShellAssignments("KEY=" + raw)
+	if err != nil {
+		t.Errorf("%v: %v", raw, err)
+	}
+	expectEqualStrings(t, expected, kvmap["KEY"])
+}
+
+func expectShellError(t *testing.T, contents string, expectedLine string) {
+	_, err := parseShellAssignments(contents)
+	if err == nil {
+		t.Errorf("Expected an error parsing [%v]", contents)
+		return
+	}
+	if !strings.Contains(err.Error(), expectedLine) {
+		t.Errorf("Expected [%v] in error [%v]", expectedLine, err)
+	}
+}
+
+func TestShellValueQuoting(t *testing.T) {
+	expectShellValue(t, "plain", `plain`)
+	expectShellValue(t, "double quoted", `"double quoted"`)
+	expectShellValue(t, "single quoted", `'single quoted'`)
+	expectShellValue(t, "", `""`)
+	expectShellValue(t, `back\slash "quote" $dollar `+"`tick`", `"back\\slash \"quote\" \$dollar \`+"`tick\\`"+`"`)
+	expectShellValue(t, `no \escapes in 'single' quotes`, `'no \escapes in '"'single'"' quotes'`)
+	expectShellValue(t, "a=b=c", `a=b=c`)
+	expectShellValue(t, "Company's OS", `"Company's OS"`)
+	expectShellValue(t, "escaped space", `escaped\ space`)
+	expectShellValue(t, "commented", `commented   # a comment`)
+	expectShellValue(t, "concatenated", `con"cat"'enated'`)
+}
+
+func TestShellAssignmentsSkipsCommentsAndBlankLines(t *testing.T) {
+	kvmap, err := parseShellAssignments(`# A comment
+   # An indented comment
+
+ID=vendor
+NAME='Vendor Linux'   
+`)
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualInts(t, 2, len(kvmap))
+	expectEqualStrings(t, "vendor", kvmap["ID"])
+	expectEqualStrings(t, "Vendor Linux", kvmap["NAME"])
+}
+
+func TestShellAssignmentsReportsMalformedLines(t *testing.T) {
+	expectShellError(t, "ID=ok\nNAME=Two words\n", "line 2: unquoted whitespace")
+	expectShellError(t, "NAME=\"unterminated\n", "line 1: unterminated double quote")
+	expectShellError(t, "\nNAME='unterminated\n", "line 2: unterminated single quote")
+	expectShellError(t, "NAME=\"$HOME\"\n", "line 1: unescaped '$'")
+	expectShellError(t, "NAME=`uname`\n", "line 1: unquoted '`'")
+	expectShellError(t, "just some text\n", "line 1: missing '='")
+	expectShellError(t, "BAD KEY=value\n", "line 1: invalid variable name")
+	expectShellError(t, "1KEY=value\n", "line 1: invalid variable name")
+}
+
+func TestShellAssignmentsKeepsValidLinesOnError(t *testing.T) {
+	kvmap, err := parseShellAssignments("ID=ok\nNAME=Two words\nVERSION_ID=1\n")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	expectEqualStrings(t, "ok", kvmap["ID"])
+	expectEqualStrings(t, "1", kvmap["VERSION_ID"])
+	if _, ok := kvmap["NAME"]; ok {
+		t.Error("Expected the malformed NAME line to be skipped")
+	}
+}
+
+func TestSingleQuotedVendorOSRelease(t *testing.T) {
+	osRelease := `NAME='Vendor Appliance OS'
+ID='vendor-os'
+ID_LIKE='rhel centos fedora'
+VERSION_ID='4.2'
+PRETTY_NAME='Vendor Appliance OS 4.2 "Stable"'
+`
+
+	info := new(OSInfo)
+	if err := parseEtcOSRelease(info, osRelease); err != nil {
+		t.Error(err)
+	}
+
+	expectEqualStrings(t, "vendor-os", info.ID)
+	expectEqualStrings(t, "4.2", info.Version)
+	expectEqualStrings(t, "Vendor Appliance OS", info.Name)
+	expectEqualStrings(t, `Vendor Appliance OS 4.2 "Stable"`, info.OSRelease.PrettyName)
+	expectEqualInts(t, 3, len(info.OSRelease.IDLike))
+}
+
+func TestUsrLibOSReleaseFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"usr/lib/os-release": &fstest.MapFile{Data: []byte(`NAME="Flatcar Container Linux by Kinvolk"
+ID=flatcar
+ID_LIKE=coreos
+VERSION=3815.2.0
+VERSION_ID=3815.2.0
+BUILD_ID=2024-03-20-1942
+SYSEXT_LEVEL=1.0
+PRETTY_NAME="Flatcar Container Linux by Kinvolk 3815.2.0 (Oklo)"
+`)},
+	}
+
+	info, err := GetOSInfoFromFS(fsys)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectEqualStrings(t, "flatcar", info.ID)
+	expectEqualStrings(t, "3815.2.0", info.Version)
+	expectEqualStrings(t, "Flatcar Container Linux by Kinvolk", info.Name)
+	expectEqualStrings(t, "2024-03-20-1942", info.OSRelease.BuildID)
+}
+
+func TestEtcOSReleaseTakesPrecedence(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/os-release":     &fstest.MapFile{Data: []byte("ID=etc\n")},
+		"usr/lib/os-release": &fstest.MapFile{Data: []byte("ID=usrlib\n")},
+	}
+
+	info, err := GetOSInfoFromFS(fsys)
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "etc", info.ID)
+}
+
+func TestExtensionReleases(t *testing.T) {
+	fsys := fstest.MapFS{
+		"usr/lib/os-release": &fstest.MapFile{Data: []byte("ID=fedora\nVERSION_ID=40\n")},
+		"usr/lib/extension-release.d/extension-release.docker": &fstest.MapFile{Data: []byte(`ID=_any
+SYSEXT_ID=docker
+SYSEXT_VERSION_ID=24.0.9
+SYSEXT_SCOPE="system portable"
+ARCHITECTURE=x86-64
+`)},
+		"usr/lib/extension-release.d/extension-release.debug-tools": &fstest.MapFile{Data: []byte(`ID=fedora
+VERSION_ID=40
+SYSEXT_LEVEL=1.0
+`)},
+		"usr/lib/extension-release.d/README": &fstest.MapFile{Data: []byte("Not an extension\n")},
+		"etc/extension-release.d/extension-release.site-config": &fstest.MapFile{Data: []byte(`ID=fedora
+CONFEXT_ID=site-config
+CONFEXT_VERSION_ID=7
+`)},
+	}
+
+	info, err := GetOSInfoFromFS(fsys)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(info.Extensions) != 3 {
+		t.Fatalf("Expected 3 extensions but got %v", len(info.Extensions))
+	}
+
+	debugTools := info.Extensions[0]
+	expectEqualStrings(t, "debug-tools", debugTools.Name)
+	expectEqualStrings(t, "sysext", debugTools.Type)
+	expectEqualStrings(t, "fedora", debugTools.ID)
+	expectEqualStrings(t, "40", debugTools.VersionID)
+	expectEqualStrings(t, "1.0", debugTools.Level)
+
+	docker := info.Extensions[1]
+	expectEqualStrings(t, "docker", docker.Name)
+	expectEqualStrings(t, "sysext", docker.Type)
+	expectEqualStrings(t, "_any", docker.ID)
+	expectEqualStrings(t, "docker", docker.ExtensionID)
+	expectEqualStrings(t, "24.0.9", docker.ExtensionVersionID)
+	expectEqualStrings(t, "system portable", docker.Scope)
+	expectEqualStrings(t, "x86-64", docker.Architecture)
+
+	siteConfig := info.Extensions[2]
+	expectEqualStrings(t, "site-config", siteConfig.Name)
+	expectEqualStrings(t, "confext", siteConfig.Type)
+	expectEqualStrings(t, "site-config", siteConfig.ExtensionID)
+	expectEqualStrings(t, "7", siteConfig.ExtensionVersionID)
+}
@@ -0,0 +1,43 @@
+package osinfo
+
+import "io/fs"
+
+// Native package managers are probed in order, from the files they install.
+// Front-ends come before the lower level tools they drive (apt before dpkg,
+// dnf before yum and rpm), and bare package databases come last so that
+// images stripped of their tools (such as distroless) are still recognized.
+var packageManagerProbes = []struct {
+	paths   []string
+	manager string
+	format  string
+}{
+	{[]string{"usr/bin/apt-get", "usr/bin/apt"}, "apt", "deb"},
+	{[]string{"usr/bin/dnf", "usr/bin/dnf5", "usr/bin/dnf-3"}, "dnf", "rpm"},
+	{[]string{"usr/bin/yum"}, "yum", "rpm"},
+	{[]string{"usr/bin/zypper"}, "zypper", "rpm"},
+	{[]string{"sbin/apk", "usr/sbin/apk", "usr/bin/apk"}, "apk", "apk"},
+	{[]string{"usr/bin/pacman"}, "pacman", "pkg.tar"},
+	{[]string{"usr/bin/emerge"}, "emerge", "ebuild"},
+	{[]string{"usr/bin/xbps-install", "usr/sbin/xbps-install"}, "xbps", "xbps"},
+	{[]string{"usr/sbin/pkg"}, "pkg", "pkg"}, // FreeBSD
+	{[]string{"run/current-system/sw/bin/nix", "nix/var/nix/profiles"}, "nix", "nar"},
+	{[]string{"opt/homebrew/bin/brew", "usr/local/bin/brew"}, "brew", "bottle"}, // macOS
+
+	{[]string{"var/lib/dpkg/status", "var/lib/dpkg/status.d"}, "dpkg", "deb"},
+	{[]string{"usr/lib/sysimage/rpm", "var/lib/rpm"}, "rpm", "rpm"},
+	{[]string{"lib/apk/db/installed"}, "apk", "apk"},
+	{[]string{"var/lib/pacman/local"}, "pacman", "pkg.tar"},
+}
+
+// detectPackageManager returns the native package manager and binary package
+// format of the system rooted at fsys, or empty strings if none was found.
+func detectPackageManager(fsys fs.FS) (manager, format string) {
+	for _, probe := range packageManagerProbes {
+		for _, path := range probe.paths {
+			if _, err := fs.Stat(fsys, path); err == nil {
+				return probe.manager, probe.format
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,82 @@
+package osinfo
+
+import "testing"
+
+func expectPackageManager(t *testing.T, paths []string, manager, format string) {
+	files := make(map[string]string)
+	for _, path := range paths {
+		files[path] = ""
+	}
+	actualManager, actualFormat := detectPackageManager(legacyFS(files))
+	expectEqualStrings(t, manager, actualManager)
+	expectEqualStrings(t, format, actualFormat)
+}
+
+func TestPackageManagerApt(t *testing.T) {
+	expectPackageManager(t, []string{"usr/bin/apt-get", "usr/bin/dpkg", "var/lib/dpkg/status"}, "apt", "deb")
+}
+
+func TestPackageManagerDnfOverYum(t *testing.T) {
+	expectPackageManager(t, []string{"usr/bin/yum", "usr/bin/dnf", "usr/bin/rpm", "var/lib/rpm/rpmdb.sqlite"}, "dnf", "rpm")
+}
+
+func TestPackageManagerYum(t *testing.T) {
+	expectPackageManager(t, []string{"usr/bin/yum", "usr/bin/rpm", "var/lib/rpm/Packages"}, "yum", "rpm")
+}
+
+func TestPackageManagerZypper(t *testing.T) {
+	expectPackageManager(t, []string{"usr/bin/zypper", "usr/bin/rpm", "usr/lib/sysimage/rpm/Packages.db"}, "zypper", "rpm")
+}
+
+func TestPackageManagerApk(t *testing.T) {
+	expectPackageManager(t, []string{"sbin/apk", "lib/apk/db/installed"}, "apk", "apk")
+}
+
+func TestPackageManagerPacman(t *testing.T) {
+	expectPackageManager(t, []string{"usr/bin/pacman", "var/lib/pacman/local/ALPM_DB_VERSION"}, "pacman", "pkg.tar")
+}
+
+func TestPackageManagerEmerge(t *testing.T) {
+	expectPackageManager(t, []string{"usr/bin/emerge"}, "emerge", "ebuild")
+}
+
+func TestPackageManagerXbps(t *testing.T) {
+	expectPackageManager(t, []string{"usr/bin/xbps-install"}, "xbps", "xbps")
+}
+
+func TestPackageManagerNix(t *testing.T) {
+	expectPackageManager(t, []string{"run/current-system/sw/bin/nix", "nix/store/.links"}, "nix", "nar")
+}
+
+func TestPackageManagerFreeBSD(t *testing.T) {
+	expectPackageManager(t, []string{"usr/sbin/pkg", "usr/local/sbin/pkg"}, "pkg", "pkg")
+}
+
+func TestPackageManagerBrew(t *testing.T) {
+	expectPackageManager(t, []string{"opt/homebrew/bin/brew"}, "brew", "bottle")
+}
+
+func TestPackageManagerDistroless(t *testing.T) {
+	expectPackageManager(t, []string{"var/lib/dpkg/status.d/base"}, "dpkg", "deb")
+}
+
+func TestPackageManagerRpmOnly(t *testing.T) {
+	expectPackageManager(t, []string{"var/lib/rpm/rpmdb.sqlite"}, "rpm", "rpm")
+}
+
+func TestPackageManagerDerivativeWithApt(t *testing.T) {
+	// The ID says nothing about apt, but the files do
+	info, err := GetOSInfoFromFS(legacyFS(map[string]string{
+		"etc/os-release":  "ID=someos\nVERSION_ID=1\n",
+		"usr/bin/apt-get": "",
+	}))
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "apt", info.PackageManager)
+	expectEqualStrings(t, "deb", info.PackageFormat)
+}
+
+func TestPackageManagerNone(t *testing.T) {
+	expectPackageManager(t, []string{"bin/sh"}, "", "")
+}
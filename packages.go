@@ -0,0 +1,264 @@
+package osinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// Package describes an installed OS package.
+type Package struct {
+	Name         string
+	Version      string
+	Architecture string
+	// The package the binary package was built from (the same as Name when
+	// the database doesn't record it)
+	SourcePackage string
+	// The package database it was found in ("dpkg", "apk" or "pacman")
+	Manager string
+}
+
+// GetInstalledPackages lists the packages installed on the current system.
+func GetInstalledPackages() ([]Package, error) {
+	return GetInstalledPackagesFromFS(os.DirFS("/"))
+}
+
+// GetInstalledPackagesFromFS lists the packages installed on the system whose
+// root filesystem is fsys, by reading the dpkg, apk and pacman databases.
+// Missing databases are skipped. On error, the packages that could be read
+// are still returned.
+func GetInstalledPackagesFromFS(fsys fs.FS) (packages []Package, err error) {
+	addPackages := func(found []Package, readErr error) {
+		packages = append(packages, found...)
+		if readErr != nil && err == nil {
+			err = readErr
+		}
+	}
+
+	addPackages(readDpkgDatabase(fsys))
+	addPackages(readApkDatabase(fsys))
+	addPackages(readPacmanDatabase(fsys))
+	return
+}
+
+// readDatabaseFile reads a package database file, returning an empty string
+// without error if it doesn't exist.
+func readDatabaseFile(fsys fs.FS, filePath string) (string, error) {
+	contents, err := readTextFile(fsys, filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return contents, err
+}
+
+// splitStanzas splits RFC 822 style contents into blank line separated
+// stanzas, returning the line number each stanza starts at.
+func splitStanzas(contents string) (stanzas []string, lineNumbers []int) {
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			stanzas = append(stanzas, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+
+	for i, line := range strings.Split(contents, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if len(current) == 0 {
+			lineNumbers = append(lineNumbers, i+1)
+		}
+		current = append(current, line)
+	}
+	flush()
+	return
+}
+
+// parseDpkgStatus parses the contents of /var/lib/dpkg/status (or a file in
+// /var/lib/dpkg/status.d), keeping only the packages that are installed.
+func parseDpkgStatus(contents string) (packages []Package, err error) {
+	stanzas, lineNumbers := splitStanzas(contents)
+	for i, stanza := range stanzas {
+		fields := make(map[string]string)
+		for _, line := range strings.Split(stanza, "\n") {
+			if line[0] == ' ' || line[0] == '\t' {
+				// Continuation of a multi-line field such as Description
+				continue
+			}
+			if parts := strings.SplitN(line, ":", 2); len(parts) == 2 {
+				fields[parts[0]] = strings.TrimSpace(parts[1])
+			}
+		}
+
+		if fields["Package"] == "" {
+			if err == nil {
+				err = fmt.Errorf("line %v: Package field missing", lineNumbers[i])
+			}
+			continue
+		}
+		// Distroless images omit Status from status.d
+		if status, ok := fields["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+
+		pkg := Package{
+			Name:          fields["Package"],
+			Version:       fields["Version"],
+			Architecture:  fields["Architecture"],
+			SourcePackage: fields["Package"],
+			Manager:       "dpkg",
+		}
+		// The source can carry its own version, such as "glibc (2.36-9)"
+		if source := strings.Fields(fields["Source"]); len(source) > 0 {
+			pkg.SourcePackage = source[0]
+		}
+		packages = append(packages, pkg)
+	}
+	return
+}
+
+func readDpkgDatabase(fsys fs.FS) (packages []Package, err error) {
+	filePaths := []string{"var/lib/dpkg/status"}
+	if entries, readErr := fs.ReadDir(fsys, "var/lib/dpkg/status.d"); readErr == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && !strings.HasSuffix(entry.Name(), ".md5sums") {
+				filePaths = append(filePaths, path.Join("var/lib/dpkg/status.d", entry.Name()))
+			}
+		}
+	}
+
+	for _, filePath := range filePaths {
+		contents, readErr := readDatabaseFile(fsys, filePath)
+		if readErr != nil {
+			if err == nil {
+				err = readErr
+			}
+			continue
+		}
+		found, parseErr := parseDpkgStatus(contents)
+		packages = append(packages, found...)
+		if parseErr != nil && err == nil {
+			err = fmt.Errorf("/%v: %v", filePath, parseErr)
+		}
+	}
+	return
+}
+
+// parseApkInstalled parses the contents of /lib/apk/db/installed.
+func parseApkInstalled(contents string) (packages []Package, err error) {
+	stanzas, lineNumbers := splitStanzas(contents)
+	for i, stanza := range stanzas {
+		var pkg Package
+		for _, line := range strings.Split(stanza, "\n") {
+			if len(line) < 2 || line[1] != ':' {
+				continue
+			}
+			value := line[2:]
+			switch line[0] {
+			case 'P':
+				pkg.Name = value
+			case 'V':
+				pkg.Version = value
+			case 'A':
+				pkg.Architecture = value
+			case 'o':
+				pkg.SourcePackage = value
+			}
+		}
+
+		if pkg.Name == "" {
+			if err == nil {
+				err = fmt.Errorf("line %v: P: field missing", lineNumbers[i])
+			}
+			continue
+		}
+		if pkg.SourcePackage == "" {
+			pkg.SourcePackage = pkg.Name
+		}
+		pkg.Manager = "apk"
+		packages = append(packages, pkg)
+	}
+	return
+}
+
+func readApkDatabase(fsys fs.FS) ([]Package, error) {
+	contents, err := readDatabaseFile(fsys, "lib/apk/db/installed")
+	if err != nil {
+		return nil, err
+	}
+	packages, err := parseApkInstalled(contents)
+	if err != nil {
+		err = fmt.Errorf("/lib/apk/db/installed: %v", err)
+	}
+	return packages, err
+}
+
+// parsePacmanDesc parses a package's desc file from the pacman local
+// database (/var/lib/pacman/local/NAME-VERSION/desc).
+func parsePacmanDesc(contents string) (pkg Package, err error) {
+	fields := make(map[string]string)
+	key := ""
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			key = ""
+		case strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%"):
+			key = strings.Trim(line, "%")
+		case key != "" && fields[key] == "":
+			// Only keep the first value of lists such as %LICENSE%
+			fields[key] = line
+		}
+	}
+
+	if fields["NAME"] == "" {
+		return pkg, fmt.Errorf("%%NAME%% missing")
+	}
+	pkg = Package{
+		Name:          fields["NAME"],
+		Version:       fields["VERSION"],
+		Architecture:  fields["ARCH"],
+		SourcePackage: fields["BASE"],
+		Manager:       "pacman",
+	}
+	if pkg.SourcePackage == "" {
+		pkg.SourcePackage = pkg.Name
+	}
+	return
+}
+
+func readPacmanDatabase(fsys fs.FS) (packages []Package, err error) {
+	entries, readErr := fs.ReadDir(fsys, "var/lib/pacman/local")
+	if readErr != nil {
+		if !errors.Is(readErr, fs.ErrNotExist) {
+			err = readErr
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		filePath := path.Join("var/lib/pacman/local", entry.Name(), "desc")
+		contents, readErr := readTextFile(fsys, filePath)
+		if readErr == nil {
+			var pkg Package
+			if pkg, readErr = parsePacmanDesc(contents); readErr == nil {
+				packages = append(packages, pkg)
+				continue
+			}
+			readErr = fmt.Errorf("/%v: %v", filePath, readErr)
+		}
+		if err == nil {
+			err = readErr
+		}
+	}
+	return
+}
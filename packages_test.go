@@ -0,0 +1,174 @@
+package osinfo
+
+import "testing"
+
+const dpkgStatus = `Package: libc6
+Status: install ok installed
+Priority: optional
+Section: libs
+Installed-Size: 12983
+Maintainer: GNU Libc Maintainers <debian-glibc@lists.debian.org>
+Architecture: amd64
+Multi-Arch: same
+Source: glibc
+Version: 2.36-9+deb12u4
+Depends: libgcc-s1
+Description: GNU C Library: Shared libraries
+ Contains the standard libraries that are used by nearly all programs on
+ the system.
+ .
+ This package includes shared versions of the standard C library.
+
+Package: removed-package
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0-1
+
+Package: libssl3
+Status: install ok installed
+Architecture: amd64
+Source: openssl (3.0.11-1~deb12u2)
+Version: 3.0.11-1~deb12u2
+Description: Secure Sockets Layer toolkit - shared libraries
+
+Package: tzdata
+Status: install ok installed
+Architecture: all
+Version: 2024a-0+deb12u1
+`
+
+const distrolessStatus = `Package: base-files
+Version: 12.4+deb12u5
+Architecture: amd64
+Maintainer: Santiago Vila <sanvila@debian.org>
+Description: Debian base system miscellaneous files
+`
+
+const apkInstalled = `C:Q1Sj8hPsOUhZ98y8Ua/UyYHVWmPAs=
+P:musl
+V:1.2.4_git20230717-r4
+A:x86_64
+S:407959
+I:651264
+T:the musl c library (libc) implementation
+U:https://musl.libc.org/
+L:MIT
+o:musl
+m:Timo Teräs <timo.teras@iki.fi>
+t:1705055440
+c:f93af038c3de7146121c2ea8124ba5ce29b4b058
+
+C:Q1Pxb6fHoFm4P+SdaYoMJ6VHQAHwE=
+P:libcrypto3
+V:3.1.4-r5
+A:x86_64
+o:openssl
+F:lib
+R:libcrypto.so.3
+`
+
+const pacmanDesc = `%NAME%
+libxcrypt
+
+%VERSION%
+4.4.36-1
+
+%BASE%
+libxcrypt
+
+%DESC%
+Modern library for one-way hashing of passwords
+
+%ARCH%
+x86_64
+
+%LICENSE%
+GPL
+LGPL
+`
+
+func expectPackage(t *testing.T, expected Package, actual Package) {
+	expectEqualStrings(t, expected.Name, actual.Name)
+	expectEqualStrings(t, expected.Version, actual.Version)
+	expectEqualStrings(t, expected.Architecture, actual.Architecture)
+	expectEqualStrings(t, expected.SourcePackage, actual.SourcePackage)
+	expectEqualStrings(t, expected.Manager, actual.Manager)
+}
+
+func TestParseDpkgStatus(t *testing.T) {
+	packages, err := parseDpkgStatus(dpkgStatus)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(packages) != 3 {
+		t.Fatalf("Expected 3 packages but got %v", len(packages))
+	}
+
+	expectPackage(t, Package{"libc6", "2.36-9+deb12u4", "amd64", "glibc", "dpkg"}, packages[0])
+	expectPackage(t, Package{"libssl3", "3.0.11-1~deb12u2", "amd64", "openssl", "dpkg"}, packages[1])
+	expectPackage(t, Package{"tzdata", "2024a-0+deb12u1", "all", "tzdata", "dpkg"}, packages[2])
+}
+
+func TestParseDpkgStatusMissingPackage(t *testing.T) {
+	packages, err := parseDpkgStatus("Package: ok\nVersion: 1\n\nVersion: 2\n")
+	if err == nil || err.Error() != "line 4: Package field missing" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	expectEqualInts(t, 1, len(packages))
+}
+
+func TestParseApkInstalled(t *testing.T) {
+	packages, err := parseApkInstalled(apkInstalled)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages but got %v", len(packages))
+	}
+
+	expectPackage(t, Package{"musl", "1.2.4_git20230717-r4", "x86_64", "musl", "apk"}, packages[0])
+	expectPackage(t, Package{"libcrypto3", "3.1.4-r5", "x86_64", "openssl", "apk"}, packages[1])
+}
+
+func TestParsePacmanDesc(t *testing.T) {
+	pkg, err := parsePacmanDesc(pacmanDesc)
+	if err != nil {
+		t.Error(err)
+	}
+	expectPackage(t, Package{"libxcrypt", "4.4.36-1", "x86_64", "libxcrypt", "pacman"}, pkg)
+
+	if _, err = parsePacmanDesc("%VERSION%\n1.0\n"); err == nil {
+		t.Error("Expected an error for a desc without %NAME%")
+	}
+}
+
+func TestGetInstalledPackagesFromFS(t *testing.T) {
+	fsys := legacyFS(map[string]string{
+		"var/lib/dpkg/status":                          dpkgStatus,
+		"var/lib/dpkg/status.d/base":                   distrolessStatus,
+		"var/lib/dpkg/status.d/base.md5sums":           "d41d8cd98f00b204e9800998ecf8427e  etc/debian_version\n",
+		"lib/apk/db/installed":                         apkInstalled,
+		"var/lib/pacman/local/ALPM_DB_VERSION":         "9\n",
+		"var/lib/pacman/local/libxcrypt-4.4.36-1/desc": pacmanDesc,
+	})
+
+	packages, err := GetInstalledPackagesFromFS(fsys)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(packages) != 7 {
+		t.Fatalf("Expected 7 packages but got %v", len(packages))
+	}
+
+	expectPackage(t, Package{"base-files", "12.4+deb12u5", "amd64", "base-files", "dpkg"}, packages[3])
+	expectPackage(t, Package{"musl", "1.2.4_git20230717-r4", "x86_64", "musl", "apk"}, packages[4])
+	expectPackage(t, Package{"libxcrypt", "4.4.36-1", "x86_64", "libxcrypt", "pacman"}, packages[6])
+}
+
+func TestGetInstalledPackagesFromEmptyFS(t *testing.T) {
+	packages, err := GetInstalledPackagesFromFS(legacyFS(nil))
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualInts(t, 0, len(packages))
+}
@@ -0,0 +1,272 @@
+package osinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// parsePlistStrings decodes a property list in either XML or binary format,
+// returning the string values of its top level dictionary. Values of other
+// types (numbers, nested dictionaries etc) are ignored.
+func parsePlistStrings(data []byte) (map[string]string, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return parseBinaryPlistStrings(data)
+	}
+	return parseXMLPlistStrings(data)
+}
+
+func parseXMLPlistStrings(data []byte) (map[string]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	result := make(map[string]string)
+
+	// Find the top level <dict>
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("Error: No dictionary found in plist")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "dict" {
+			break
+		}
+	}
+
+	key := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch element := token.(type) {
+		case xml.EndElement:
+			// End of the top level <dict>
+			return result, nil
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "key":
+				if err = decoder.DecodeElement(&key, &element); err != nil {
+					return nil, err
+				}
+			case "string":
+				var value string
+				if err = decoder.DecodeElement(&value, &element); err != nil {
+					return nil, err
+				}
+				result[key] = value
+			default:
+				if err = decoder.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+}
+
+// See https://opensource.apple.com/source/CF/CF-1153.18/CFBinaryPList.c
+type binaryPlist struct {
+	data          []byte
+	offsetSize    int
+	refSize       int
+	objectCount   uint64
+	offsetsOffset uint64
+}
+
+func readBigEndian(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func (p *binaryPlist) slice(offset uint64, length uint64) ([]byte, error) {
+	if offset > uint64(len(p.data)) || length > uint64(len(p.data))-offset {
+		return nil, fmt.Errorf("Error: Binary plist offset %v out of range", offset)
+	}
+	return p.data[offset : offset+length], nil
+}
+
+// fits returns true if count items of size bytes fit in the data after
+// offset, without overflowing count*size.
+func (p *binaryPlist) fits(offset uint64, count uint64, size uint64) bool {
+	return offset <= uint64(len(p.data)) && count <= (uint64(len(p.data))-offset)/size
+}
+
+func (p *binaryPlist) objectOffset(ref uint64) (uint64, error) {
+	if ref >= p.objectCount {
+		return 0, fmt.Errorf("Error: Binary plist object %v out of range", ref)
+	}
+	entry, err := p.slice(p.offsetsOffset+ref*uint64(p.offsetSize), uint64(p.offsetSize))
+	if err != nil {
+		return 0, err
+	}
+	return readBigEndian(entry), nil
+}
+
+// readLength returns the length of the object at offset, and where its
+// contents start.
+func (p *binaryPlist) readLength(offset uint64) (length uint64, start uint64, err error) {
+	var marker []byte
+	if marker, err = p.slice(offset, 1); err != nil {
+		return
+	}
+	length = uint64(marker[0] & 0x0f)
+	start = offset + 1
+	if length != 0x0f {
+		return
+	}
+
+	// Longer lengths are stored in a following integer object
+	var intMarker []byte
+	if intMarker, err = p.slice(start, 1); err != nil {
+		return
+	}
+	if intMarker[0]&0xf0 != 0x10 {
+		err = fmt.Errorf("Error: Invalid binary plist length at offset %v", offset)
+		return
+	}
+	size := uint64(1) << (intMarker[0] & 0x0f)
+	var lengthBytes []byte
+	if lengthBytes, err = p.slice(start+1, size); err != nil {
+		return
+	}
+	length = readBigEndian(lengthBytes)
+	start += 1 + size
+	return
+}
+
+// readString returns the string object ref, and false if it isn't a string.
+func (p *binaryPlist) readString(ref uint64) (string, bool, error) {
+	offset, err := p.objectOffset(ref)
+	if err != nil {
+		return "", false, err
+	}
+	marker, err := p.slice(offset, 1)
+	if err != nil {
+		return "", false, err
+	}
+
+	switch marker[0] & 0xf0 {
+	case 0x50: // ASCII
+		length, start, err := p.readLength(offset)
+		if err != nil {
+			return "", false, err
+		}
+		contents, err := p.slice(start, length)
+		return string(contents), err == nil, err
+	case 0x60: // UTF-16BE
+		length, start, err := p.readLength(offset)
+		if err != nil {
+			return "", false, err
+		}
+		if !p.fits(start, length, 2) {
+			return "", false, fmt.Errorf("Error: Binary plist string too long at offset %v", offset)
+		}
+		contents, err := p.slice(start, length*2)
+		if err != nil {
+			return "", false, err
+		}
+		units := make([]uint16, length)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(contents[i*2:])
+		}
+		return string(utf16.Decode(units)), true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+func parseBinaryPlistStrings(data []byte) (map[string]string, error) {
+	const trailerSize = 32
+	if len(data) < len("bplist00")+trailerSize {
+		return nil, fmt.Errorf("Error: Binary plist too short")
+	}
+	trailer := data[len(data)-trailerSize:]
+	p := &binaryPlist{
+		data:          data,
+		offsetSize:    int(trailer[6]),
+		refSize:       int(trailer[7]),
+		objectCount:   binary.BigEndian.Uint64(trailer[8:]),
+		offsetsOffset: binary.BigEndian.Uint64(trailer[24:]),
+	}
+	if p.offsetSize < 1 || p.offsetSize > 8 || p.refSize < 1 || p.refSize > 8 {
+		return nil, fmt.Errorf("Error: Invalid binary plist trailer")
+	}
+	if !p.fits(p.offsetsOffset, p.objectCount, uint64(p.offsetSize)) {
+		return nil, fmt.Errorf("Error: Binary plist offset table out of range")
+	}
+
+	topOffset, err := p.objectOffset(binary.BigEndian.Uint64(trailer[16:]))
+	if err != nil {
+		return nil, err
+	}
+	marker, err := p.slice(topOffset, 1)
+	if err != nil {
+		return nil, err
+	}
+	if marker[0]&0xf0 != 0xd0 {
+		return nil, fmt.Errorf("Error: Top level binary plist object is not a dictionary")
+	}
+	count, start, err := p.readLength(topOffset)
+	if err != nil {
+		return nil, err
+	}
+	if count > p.objectCount || !p.fits(start, count, 2*uint64(p.refSize)) {
+		return nil, fmt.Errorf("Error: Invalid binary plist dictionary size %v", count)
+	}
+	refs, err := p.slice(start, count*2*uint64(p.refSize))
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys are all stored before the values
+	result := make(map[string]string)
+	for i := uint64(0); i < count; i++ {
+		keyRef := readBigEndian(refs[i*uint64(p.refSize) : (i+1)*uint64(p.refSize)])
+		valueRef := readBigEndian(refs[(count+i)*uint64(p.refSize) : (count+i+1)*uint64(p.refSize)])
+		key, isString, err := p.readString(keyRef)
+		if err != nil {
+			return nil, err
+		}
+		if !isString {
+			return nil, fmt.Errorf("Error: Binary plist dictionary key is not a string")
+		}
+		value, isString, err := p.readString(valueRef)
+		if err != nil {
+			return nil, err
+		}
+		if isString {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+// parseMacSystemVersion fills info from the contents of
+// /System/Library/CoreServices/SystemVersion.plist
+func parseMacSystemVersion(info *OSInfo, data []byte) error {
+	values, err := parsePlistStrings(data)
+	if err != nil {
+		return err
+	}
+
+	productVersion := strings.TrimSpace(values["ProductVersion"])
+	if productVersion == "" {
+		return fmt.Errorf("Error: ProductVersion missing from SystemVersion.plist")
+	}
+	// The name comes from the version, since ProductName still says
+	// "Mac OS X" up to Catalina
+	if err = parseMacSWVers(info, productVersion, values["ProductBuildVersion"]); err != nil {
+		return err
+	}
+	// Rapid Security Responses, such as "(a)"
+	info.VersionExtra = values["ProductVersionExtra"]
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build go1.18
+// +build go1.18
+
+package osinfo
+
+import "testing"
+
+func FuzzParseBinaryPlist(f *testing.F) {
+	for _, name := range []string{
+		"SystemVersion-14.5.bplist",
+		"SystemVersion-10.6.8.bplist",
+		"overflow-dict-count.bplist",
+	} {
+		f.Add(readFixture(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Must return an error rather than panic
+		parseBinaryPlistStrings(data)
+	})
+}
@@ -0,0 +1,149 @@
+package osinfo
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestXMLPlist(t *testing.T) {
+	values, err := parsePlistStrings(readFixture(t, "SystemVersion-13.4.1c.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualInts(t, 7, len(values))
+	expectEqualStrings(t, "13.4.1", values["ProductVersion"])
+	expectEqualStrings(t, "(c)", values["ProductVersionExtra"])
+	expectEqualStrings(t, "22F770820d", values["ProductBuildVersion"])
+}
+
+func TestXMLPlistSkipsOtherTypes(t *testing.T) {
+	values, err := parsePlistStrings([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Number</key>
+	<integer>42</integer>
+	<key>Nested</key>
+	<dict>
+		<key>ProductName</key>
+		<string>Nested</string>
+	</dict>
+	<key>Flag</key>
+	<true/>
+	<key>ProductName</key>
+	<string>macOS</string>
+</dict>
+</plist>
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualInts(t, 1, len(values))
+	expectEqualStrings(t, "macOS", values["ProductName"])
+}
+
+func TestBinaryPlist(t *testing.T) {
+	values, err := parsePlistStrings(readFixture(t, "SystemVersion-14.5.bplist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualInts(t, 7, len(values))
+	expectEqualStrings(t, "14.5", values["ProductVersion"])
+	expectEqualStrings(t, "23F79", values["ProductBuildVersion"])
+	expectEqualStrings(t, "6E2A1C1A-1F4B-11EF-9A48-2E30B5A5B05C", values["BuildID"])
+}
+
+func TestBinaryPlistLongAndUTF16Strings(t *testing.T) {
+	values, err := parsePlistStrings(readFixture(t, "SystemVersion-10.6.8.bplist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nested and Number are not strings
+	expectEqualInts(t, 4, len(values))
+	expectEqualStrings(t, "1983-2011 Apple Inc. ©", values["ProductCopyright"])
+	expectEqualStrings(t, "10K549", values["ProductBuildVersion"])
+}
+
+func TestCorruptPlists(t *testing.T) {
+	valid := readFixture(t, "SystemVersion-14.5.bplist")
+	for _, data := range [][]byte{
+		[]byte(""),
+		[]byte("<plist><array></array></plist>"),
+		[]byte("bplist00"),
+		valid[:len(valid)/2],
+		append(append([]byte{}, valid[:len(valid)-8]...), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff),
+	} {
+		if _, err := parsePlistStrings(data); err == nil {
+			t.Errorf("Expected an error parsing %q", data)
+		}
+	}
+}
+
+func TestBinaryPlistOverflow(t *testing.T) {
+	// A dictionary of 1<<63 entries, whose size overflows when multiplied
+	// by the reference size
+	if _, err := parsePlistStrings(readFixture(t, "overflow-dict-count.bplist")); err == nil {
+		t.Error("Expected an error parsing an oversized dictionary")
+	}
+}
+
+func TestMacSystemVersionRapidSecurityResponse(t *testing.T) {
+	info := new(OSInfo)
+	if err := parseMacSystemVersion(info, readFixture(t, "SystemVersion-13.4.1c.plist")); err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "macOS", info.Name)
+	expectEqualStrings(t, "13.4.1", info.Version)
+	expectEqualStrings(t, "(c)", info.VersionExtra)
+	expectEqualStrings(t, "22F770820d", info.Build)
+	expectEqualStrings(t, "Ventura", info.Codename)
+}
+
+func TestMacSystemVersionOldName(t *testing.T) {
+	info := new(OSInfo)
+	if err := parseMacSystemVersion(info, readFixture(t, "SystemVersion-10.6.8.bplist")); err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "Mac OS X", info.Name)
+	expectEqualStrings(t, "Snow Leopard", info.Codename)
+}
+
+func TestMacSystemVersionRenamed(t *testing.T) {
+	// Sierra to Catalina are macOS, but their plist still says Mac OS X
+	info := new(OSInfo)
+	if err := parseMacSystemVersion(info, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>ProductBuildVersion</key>
+	<string>19H2026</string>
+	<key>ProductName</key>
+	<string>Mac OS X</string>
+	<key>ProductVersion</key>
+	<string>10.15.7</string>
+</dict>
+</plist>
+`)); err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "macOS", info.Name)
+	expectEqualStrings(t, "Catalina", info.Codename)
+}
+
+func TestGetOSInfoFromMacFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		macSystemVersionPath:    &fstest.MapFile{Data: readFixture(t, "SystemVersion-14.5.bplist")},
+		"opt/homebrew/bin/brew": &fstest.MapFile{},
+	}
+
+	info, err := GetOSInfoFromFS(fsys)
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualStrings(t, "darwin", info.Family)
+	expectEqualStrings(t, "darwin", info.ID)
+	expectEqualStrings(t, "macOS", info.Name)
+	expectEqualStrings(t, "14.5", info.Version)
+	expectEqualStrings(t, "23F79", info.Build)
+	expectEqualStrings(t, "Sonoma", info.Codename)
+	expectEqualStrings(t, "", info.VersionExtra)
+	expectEqualStrings(t, "brew", info.PackageManager)
+}
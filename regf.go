@@ -0,0 +1,355 @@
+package osinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Windows registry hive files ("regf" format), as found in
+// Windows\System32\config. Only what's needed to read the values of a key is
+// implemented. See https://github.com/msuhanov/regf/blob/master/Windows%20registry%20file%20format%20specification.md
+
+const (
+	regfBaseBlockSize = 4096
+	// Data larger than this is split into segments by a "db" record
+	regfBigDataSegmentSize = 16344
+
+	regfKeyCompressedName   = 0x0020
+	regfValueCompressedName = 0x0001
+	regfDataInline          = 0x80000000
+)
+
+var registryValueTypes = []string{
+	"REG_NONE",
+	"REG_SZ",
+	"REG_EXPAND_SZ",
+	"REG_BINARY",
+	"REG_DWORD",
+	"REG_DWORD_BIG_ENDIAN",
+	"REG_LINK",
+	"REG_MULTI_SZ",
+	"REG_RESOURCE_LIST",
+	"REG_FULL_RESOURCE_DESCRIPTOR",
+	"REG_RESOURCE_REQUIREMENTS_LIST",
+	"REG_QWORD",
+}
+
+type registryHive struct {
+	data         []byte
+	minorVersion uint32
+}
+
+func (h *registryHive) slice(offset uint64, length uint64) ([]byte, error) {
+	if offset > uint64(len(h.data)) || length > uint64(len(h.data))-offset {
+		return nil, fmt.Errorf("Error: Registry hive offset %v out of range", offset)
+	}
+	return h.data[offset : offset+length], nil
+}
+
+// cell returns the contents of the cell at offset, which is relative to the
+// start of the hive bins.
+func (h *registryHive) cell(offset uint32) ([]byte, error) {
+	start := uint64(regfBaseBlockSize) + uint64(offset)
+	header, err := h.slice(start, 4)
+	if err != nil {
+		return nil, err
+	}
+	// Allocated cells have a negative size
+	size := -int32(binary.LittleEndian.Uint32(header))
+	if size < 4 {
+		return nil, fmt.Errorf("Error: Registry hive cell %v is not allocated", offset)
+	}
+	return h.slice(start+4, uint64(size)-4)
+}
+
+func (h *registryHive) signedCell(offset uint32, signature string, minLength int) ([]byte, error) {
+	cell, err := h.cell(offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(cell) < minLength || string(cell[:2]) != signature {
+		return nil, fmt.Errorf("Error: Registry hive cell %v is not a valid %v record", offset, signature)
+	}
+	return cell, nil
+}
+
+type registryKeyNode struct {
+	name          string
+	subkeyCount   uint32
+	subkeysOffset uint32
+	valueCount    uint32
+	valuesOffset  uint32
+}
+
+func (h *registryHive) keyNode(offset uint32) (*registryKeyNode, error) {
+	const headerSize = 76
+	cell, err := h.signedCell(offset, "nk", headerSize)
+	if err != nil {
+		return nil, err
+	}
+	nameLength := int(binary.LittleEndian.Uint16(cell[72:]))
+	if headerSize+nameLength > len(cell) {
+		return nil, fmt.Errorf("Error: Registry hive key name out of range at cell %v", offset)
+	}
+	flags := binary.LittleEndian.Uint16(cell[2:])
+	return &registryKeyNode{
+		name:          decodeRegistryName(cell[headerSize:headerSize+nameLength], flags&regfKeyCompressedName != 0),
+		subkeyCount:   binary.LittleEndian.Uint32(cell[20:]),
+		subkeysOffset: binary.LittleEndian.Uint32(cell[28:]),
+		valueCount:    binary.LittleEndian.Uint32(cell[36:]),
+		valuesOffset:  binary.LittleEndian.Uint32(cell[40:]),
+	}, nil
+}
+
+// subkeyOffsets returns the offsets of the key nodes in a subkey list, which
+// is either a leaf (li, lf, lh) or an index root (ri) of leaves.
+func (h *registryHive) subkeyOffsets(offset uint32, allowIndexRoot bool) ([]uint32, error) {
+	cell, err := h.cell(offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(cell) < 4 {
+		return nil, fmt.Errorf("Error: Registry hive subkey list too short at cell %v", offset)
+	}
+	count := int(binary.LittleEndian.Uint16(cell[2:]))
+
+	var entrySize int
+	switch string(cell[:2]) {
+	case "li", "ri":
+		entrySize = 4
+	case "lf", "lh":
+		entrySize = 8
+	default:
+		return nil, fmt.Errorf("Error: Registry hive cell %v is not a subkey list", offset)
+	}
+	if 4+count*entrySize > len(cell) {
+		return nil, fmt.Errorf("Error: Registry hive subkey list out of range at cell %v", offset)
+	}
+
+	var offsets []uint32
+	for i := 0; i < count; i++ {
+		entry := binary.LittleEndian.Uint32(cell[4+i*entrySize:])
+		if string(cell[:2]) != "ri" {
+			offsets = append(offsets, entry)
+			continue
+		}
+		// Index roots only ever point to leaves
+		if !allowIndexRoot {
+			return nil, fmt.Errorf("Error: Nested registry hive index root at cell %v", offset)
+		}
+		leafOffsets, err := h.subkeyOffsets(entry, false)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, leafOffsets...)
+	}
+	return offsets, nil
+}
+
+func (h *registryHive) subkey(key *registryKeyNode, name string) (*registryKeyNode, error) {
+	if key.subkeyCount == 0 {
+		return nil, nil
+	}
+	offsets, err := h.subkeyOffsets(key.subkeysOffset, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, offset := range offsets {
+		subkey, err := h.keyNode(offset)
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(subkey.name, name) {
+			return subkey, nil
+		}
+	}
+	return nil, nil
+}
+
+func (h *registryHive) values(key *registryKeyNode) (registryValues, error) {
+	values := make(registryValues)
+	if key.valueCount == 0 {
+		return values, nil
+	}
+	list, err := h.cell(key.valuesOffset)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(key.valueCount)*4 > uint64(len(list)) {
+		return nil, fmt.Errorf("Error: Registry hive value list out of range at cell %v", key.valuesOffset)
+	}
+	for i := uint32(0); i < key.valueCount; i++ {
+		value, err := h.value(binary.LittleEndian.Uint32(list[i*4:]))
+		if err != nil {
+			return nil, err
+		}
+		values[strings.ToLower(value.name)] = value
+	}
+	return values, nil
+}
+
+func (h *registryHive) value(offset uint32) (value registryValue, err error) {
+	const headerSize = 20
+	var cell []byte
+	if cell, err = h.signedCell(offset, "vk", headerSize); err != nil {
+		return
+	}
+	nameLength := int(binary.LittleEndian.Uint16(cell[2:]))
+	if headerSize+nameLength > len(cell) {
+		err = fmt.Errorf("Error: Registry hive value name out of range at cell %v", offset)
+		return
+	}
+	flags := binary.LittleEndian.Uint16(cell[16:])
+	value.name = decodeRegistryName(cell[headerSize:headerSize+nameLength], flags&regfValueCompressedName != 0)
+	if value.name == "" {
+		value.name = "(Default)"
+	}
+
+	dataType := binary.LittleEndian.Uint32(cell[12:])
+	if dataType < uint32(len(registryValueTypes)) {
+		value.valueType = registryValueTypes[dataType]
+	} else {
+		value.valueType = fmt.Sprintf("REG_0x%x", dataType)
+	}
+
+	var data []byte
+	if data, err = h.valueData(cell); err != nil {
+		return
+	}
+	value.data = formatRegistryData(dataType, data)
+	return
+}
+
+func (h *registryHive) valueData(cell []byte) ([]byte, error) {
+	size := binary.LittleEndian.Uint32(cell[4:])
+	if size&regfDataInline != 0 {
+		size &^= regfDataInline
+		if size > 4 {
+			return nil, fmt.Errorf("Error: Invalid inline registry hive value size %v", size)
+		}
+		return cell[8 : 8+size], nil
+	}
+
+	offset := binary.LittleEndian.Uint32(cell[8:])
+	if size > regfBigDataSegmentSize && h.minorVersion >= 4 {
+		return h.bigData(offset, size)
+	}
+	data, err := h.cell(offset)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(size) > uint64(len(data)) {
+		return nil, fmt.Errorf("Error: Registry hive value data out of range at cell %v", offset)
+	}
+	return data[:size], nil
+}
+
+func (h *registryHive) bigData(offset uint32, size uint32) ([]byte, error) {
+	record, err := h.signedCell(offset, "db", 8)
+	if err != nil {
+		return nil, err
+	}
+	count := int(binary.LittleEndian.Uint16(record[2:]))
+	segmentsOffset := binary.LittleEndian.Uint32(record[4:])
+	segments, err := h.cell(segmentsOffset)
+	if err != nil {
+		return nil, err
+	}
+	if count*4 > len(segments) {
+		return nil, fmt.Errorf("Error: Registry hive big data list out of range at cell %v", segmentsOffset)
+	}
+
+	var data []byte
+	for i := 0; i < count && uint32(len(data)) < size; i++ {
+		segment, err := h.cell(binary.LittleEndian.Uint32(segments[i*4:]))
+		if err != nil {
+			return nil, err
+		}
+		if len(segment) > regfBigDataSegmentSize {
+			segment = segment[:regfBigDataSegmentSize]
+		}
+		data = append(data, segment...)
+	}
+	if uint32(len(data)) < size {
+		return nil, fmt.Errorf("Error: Registry hive big data truncated at cell %v", offset)
+	}
+	return data[:size], nil
+}
+
+// Compressed names are stored as Latin-1, others as UTF-16LE.
+func decodeRegistryName(data []byte, compressed bool) string {
+	if !compressed {
+		return decodeUTF16LE(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func decodeUTF16LE(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// formatRegistryData formats value data the way reg.exe prints it, so that
+// values read from a hive can be used in place of reg query output.
+func formatRegistryData(dataType uint32, data []byte) string {
+	switch dataType {
+	case 1, 2: // REG_SZ, REG_EXPAND_SZ
+		result := decodeUTF16LE(data)
+		if end := strings.IndexRune(result, 0); end >= 0 {
+			result = result[:end]
+		}
+		return result
+	case 7: // REG_MULTI_SZ
+		result := strings.TrimRight(decodeUTF16LE(data), "\x00")
+		return strings.ReplaceAll(result, "\x00", `\0`)
+	case 4: // REG_DWORD
+		if len(data) >= 4 {
+			return fmt.Sprintf("0x%x", binary.LittleEndian.Uint32(data))
+		}
+	case 5: // REG_DWORD_BIG_ENDIAN
+		if len(data) >= 4 {
+			return fmt.Sprintf("0x%x", binary.BigEndian.Uint32(data))
+		}
+	case 11: // REG_QWORD
+		if len(data) >= 8 {
+			return fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(data))
+		}
+	}
+	return strings.ToUpper(hex.EncodeToString(data))
+}
+
+// readRegistryHiveKey returns the values of the key at path (separated by
+// backslashes, relative to the root key) in the hive file data.
+func readRegistryHiveKey(data []byte, path string) (registryValues, error) {
+	if len(data) < regfBaseBlockSize || !bytes.HasPrefix(data, []byte("regf")) {
+		return nil, fmt.Errorf("Error: Not a registry hive file")
+	}
+	h := &registryHive{
+		data:         data,
+		minorVersion: binary.LittleEndian.Uint32(data[0x18:]),
+	}
+	key, err := h.keyNode(binary.LittleEndian.Uint32(data[0x24:]))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(path, `\`) {
+		if key, err = h.subkey(key, name); err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return nil, fmt.Errorf("Error: Registry key %v not found in hive", path)
+		}
+	}
+	return h.values(key)
+}
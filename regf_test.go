@@ -0,0 +1,123 @@
+package osinfo
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+const currentVersionKey = `Microsoft\Windows NT\CurrentVersion`
+
+// The hive fixtures hold the same values as the reg.exe output fixtures
+func expectHiveMatchesRegQuery(t *testing.T, hiveFixture, regQueryFixture string) registryValues {
+	values, err := readRegistryHiveKey(readFixture(t, hiveFixture), currentVersionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range parseRegistryValues(string(readFixture(t, regQueryFixture))) {
+		actual, ok := values[name]
+		if !ok {
+			t.Errorf("Value %v missing from hive", expected.name)
+			continue
+		}
+		expectEqualStrings(t, expected.name, actual.name)
+		expectEqualStrings(t, expected.valueType, actual.valueType)
+		expectEqualStrings(t, expected.data, actual.data)
+	}
+	return values
+}
+
+func TestRegistryHiveWindows11(t *testing.T) {
+	values := expectHiveMatchesRegQuery(t, "SOFTWARE-windows11-23h2.hive", "reg_query_windows11_23h2.txt")
+	expectEqualInts(t, 32, len(values))
+
+	// Stored as a big data record, in 16344 byte segments
+	bigValue := values["digitalproductid4"]
+	expectEqualStrings(t, "REG_BINARY", bigValue.valueType)
+	expectEqualInts(t, 40000, len(bigValue.data))
+	expectEqualStrings(t, "000102", bigValue.data[:6])
+	expectEqualStrings(t, "1D1E1F", bigValue.data[16344*2:16344*2+6])
+}
+
+func TestRegistryHiveWindows7(t *testing.T) {
+	// Uses li and ri subkey lists, and UTF-16 names
+	values := expectHiveMatchesRegQuery(t, "SOFTWARE-windows7-sp1.hive", "reg_query_windows7_sp1.txt")
+	expectEqualInts(t, 20, len(values))
+}
+
+func TestRegistryHiveMissingKey(t *testing.T) {
+	hive := readFixture(t, "SOFTWARE-windows7-sp1.hive")
+	_, err := readRegistryHiveKey(hive, `Microsoft\Windows NT\CurrentVersion\Winlogon\Notify`)
+	if err == nil {
+		t.Errorf("Expected an error for a missing key")
+	}
+	values, err := readRegistryHiveKey(hive, `MICROSOFT\windows nt\currentversion\Winlogon`)
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualInts(t, 0, len(values))
+}
+
+func TestRegistryHiveInvalid(t *testing.T) {
+	if _, err := readRegistryHiveKey([]byte("regf"), currentVersionKey); err == nil {
+		t.Errorf("Expected an error for a truncated hive")
+	}
+	if _, err := readRegistryHiveKey(make([]byte, 8192), currentVersionKey); err == nil {
+		t.Errorf("Expected an error for a hive without a signature")
+	}
+
+	hive := readFixture(t, "SOFTWARE-windows7-sp1.hive")
+	if _, err := readRegistryHiveKey(hive[:5000], currentVersionKey); err == nil {
+		t.Errorf("Expected an error for a truncated hive")
+	}
+
+	// Corrupt data must give an error or wrong values, but never panic
+	for i := regfBaseBlockSize; i < len(hive); i++ {
+		corrupted := append([]byte(nil), hive...)
+		corrupted[i] ^= 0xff
+		readRegistryHiveKey(corrupted, currentVersionKey)
+	}
+}
+
+func TestGetOSInfoFromRegistryHive(t *testing.T) {
+	info, err := GetOSInfoFromRegistryHive(readFixture(t, "SOFTWARE-windows11-23h2.hive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "windows", info.Family)
+	expectEqualStrings(t, "windows", info.ID)
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+	expectEqualStrings(t, "10.0", info.Version)
+	expectEqualStrings(t, "22631", info.Build)
+	expectEqualStrings(t, "23H2", info.Codename)
+	expectEqualStrings(t, "Professional", info.Windows.Edition)
+	expectEqualStrings(t, "10.0.22631.3447", info.Windows.FullBuild)
+
+	_, err = GetOSInfoFromRegistryHive([]byte("not a hive"))
+	if err == nil {
+		t.Errorf("Expected an error for an invalid hive")
+	}
+}
+
+func TestGetOSInfoFromWindowsFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"WINDOWS/system32/config/SOFTWARE": {Data: readFixture(t, "SOFTWARE-windows7-sp1.hive")},
+		"WINDOWS/system32/config/SYSTEM":   {Data: []byte("regf")},
+	}
+	info, err := GetOSInfoFromFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqualStrings(t, "windows", info.Family)
+	expectEqualStrings(t, "Windows 7 Professional Service Pack 1", info.Name)
+	expectEqualStrings(t, "6.1", info.Version)
+	expectEqualStrings(t, "7601", info.Build)
+	expectEqualStrings(t, "", info.Architecture)
+}
+
+func TestFormatRegistryData(t *testing.T) {
+	expectEqualStrings(t, "a\\0b", formatRegistryData(7, []byte("a\x00\x00\x00b\x00\x00\x00\x00\x00")))
+	expectEqualStrings(t, "0x1", formatRegistryData(5, []byte{0, 0, 0, 1}))
+	expectEqualStrings(t, "ABCD", formatRegistryData(4, []byte{0xab, 0xcd}))
+	expectEqualStrings(t, "abc", formatRegistryData(1, []byte("a\x00b\x00c\x00\x00\x00x\x00")))
+	expectEqualStrings(t, "Ünïcode", decodeRegistryName([]byte("\xdcn\xefcode"), true))
+}
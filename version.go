@@ -0,0 +1,175 @@
+package osinfo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed, comparable OS version.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	// Whatever follows the numeric components, such as "RELEASE-p4" in
+	// FreeBSD's "12.0-RELEASE-p4" or "LTS" in "22.04.3 LTS"
+	Suffix string
+	// The string the version was parsed from
+	Original string
+}
+
+var versionRE = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(.*)$`)
+
+// ParseVersion parses a version of up to three numeric components, optionally
+// followed by a suffix. Missing components are treated as 0.
+func ParseVersion(version string) (v Version, err error) {
+	v.Original = version
+	found := versionRE.FindStringSubmatch(strings.TrimSpace(version))
+	if len(found) == 0 {
+		return v, fmt.Errorf("%v: Not a version number", version)
+	}
+
+	components := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, component := range found[1:4] {
+		if component == "" {
+			continue
+		}
+		if *components[i], err = strconv.Atoi(component); err != nil {
+			return v, fmt.Errorf("%v: %v", version, err)
+		}
+	}
+	v.Suffix = strings.TrimLeft(found[4], ".-_+~ ")
+	return
+}
+
+// Compare returns -1, 0 or 1 when v is lower than, equal to or greater than
+// other. Suffixes are only compared when all numeric components are equal,
+// as described in compareVersionSuffixes.
+func (v Version) Compare(other Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+	return compareVersionSuffixes(v.Suffix, other.Suffix)
+}
+
+// AtLeast returns true if v is greater than or equal to other, ignoring
+// suffixes. For example, "12.0-RELEASE" is at least "12".
+func (v Version) AtLeast(other Version) bool {
+	other.Suffix = v.Suffix
+	return v.Compare(other) >= 0
+}
+
+func (v Version) String() string {
+	return v.Original
+}
+
+// Suffix words that don't make a version any newer, as in "12.0-RELEASE" or
+// "22.04.3 LTS"
+var neutralSuffixWords = []string{"release", "lts"}
+
+// Suffix words marking a version that comes before the release, as in
+// "14.0-ALPHA1", "14.0-RC3" or "6.10.0-rc2"
+var preReleaseSuffixRE = regexp.MustCompile(`^(alpha|beta|pre|prerelease|rc)\d*$`)
+
+// versionSuffixWords splits a suffix into lower case words, leaving out the
+// neutral ones.
+func versionSuffixWords(suffix string) (words []string, preRelease bool) {
+	for _, word := range strings.FieldsFunc(strings.ToLower(suffix), func(r rune) bool {
+		return strings.ContainsRune("-._+~ ", r)
+	}) {
+		if containsString(neutralSuffixWords, word) {
+			continue
+		}
+		if preReleaseSuffixRE.MatchString(word) {
+			preRelease = true
+		}
+		words = append(words, word)
+	}
+	return
+}
+
+// compareVersionSuffixes compares the suffixes of otherwise equal versions.
+// Pre-releases come before the release, and numbers are compared
+// numerically, so that "ALPHA" < "BETA2" < "RC1" < "" < "p9" < "p10".
+func compareVersionSuffixes(a, b string) int {
+	aWords, aPreRelease := versionSuffixWords(a)
+	bWords, bPreRelease := versionSuffixWords(b)
+	if aPreRelease != bPreRelease {
+		if aPreRelease {
+			return -1
+		}
+		return 1
+	}
+	return compareNatural(strings.Join(aWords, "."), strings.Join(bWords, "."))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+// compareNatural compares strings lexically, except for runs of digits which
+// are compared numerically.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+			aNumber, bNumber := strings.TrimLeft(aDigits, "0"), strings.TrimLeft(bDigits, "0")
+			if result := compareInts(len(aNumber), len(bNumber)); result != 0 {
+				return result
+			}
+			if result := strings.Compare(aNumber, bNumber); result != 0 {
+				return result
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+		if result := compareInts(int(a[0]), int(b[0])); result != 0 {
+			return result
+		}
+		a, b = a[1:], b[1:]
+	}
+	return compareInts(len(a), len(b))
+}
+
+// ParsedVersion parses the OS version according to the rules of its family:
+//   - Windows: Version and Build are combined, so that 10.0.22000 is Windows 11
+//   - Linux: PointVersion is used when available
+//   - Others: Version is used as is
+func (info *OSInfo) ParsedVersion() (Version, error) {
+	switch info.Family {
+	case "windows":
+		if info.Build != "" {
+			return ParseVersion(info.Version + "." + info.Build)
+		}
+	case "linux":
+		if info.PointVersion != "" {
+			return ParseVersion(info.PointVersion)
+		}
+	}
+	return ParseVersion(info.Version)
+}
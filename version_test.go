@@ -0,0 +1,120 @@
+package osinfo
+
+import "testing"
+
+func expectVersion(t *testing.T, version string, major, minor, patch int, suffix string) {
+	v, err := ParseVersion(version)
+	if err != nil {
+		t.Error(err)
+	}
+	expectEqualInts(t, major, v.Major)
+	expectEqualInts(t, minor, v.Minor)
+	expectEqualInts(t, patch, v.Patch)
+	expectEqualStrings(t, suffix, v.Suffix)
+	expectEqualStrings(t, version, v.String())
+}
+
+func expectCompare(t *testing.T, expected int, a, b string) {
+	va, err := ParseVersion(a)
+	if err != nil {
+		t.Error(err)
+	}
+	vb, err := ParseVersion(b)
+	if err != nil {
+		t.Error(err)
+	}
+	if actual := va.Compare(vb); actual != expected {
+		t.Errorf("Expected %v compared to %v to be %v but got %v", a, b, expected, actual)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	expectVersion(t, "19.10", 19, 10, 0, "")
+	expectVersion(t, "12.0-RELEASE", 12, 0, 0, "RELEASE")
+	expectVersion(t, "12.0-RELEASE-p4", 12, 0, 0, "RELEASE-p4")
+	expectVersion(t, "10.12.6", 10, 12, 6, "")
+	expectVersion(t, "3.8.0", 3, 8, 0, "")
+	expectVersion(t, "8", 8, 0, 0, "")
+	expectVersion(t, "22.04.3 LTS", 22, 4, 3, "LTS")
+	expectVersion(t, "2020.2", 2020, 2, 0, "")
+	expectVersion(t, "7.9.2009", 7, 9, 2009, "")
+}
+
+func TestParseVersionErrors(t *testing.T) {
+	for _, version := range []string{"", "bullseye/sid", "unknown", "rolling"} {
+		if _, err := ParseVersion(version); err == nil {
+			t.Errorf("Expected an error parsing [%v]", version)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	expectCompare(t, 0, "20.04", "20.04")
+	expectCompare(t, 0, "20.04", "20.4.0")
+	expectCompare(t, 1, "22.04", "20.04")
+	expectCompare(t, -1, "19.10", "20.04")
+	expectCompare(t, 1, "10.15.7", "10.15")
+	expectCompare(t, -1, "10.9", "10.10")
+	expectCompare(t, 1, "12.0-RELEASE-p4", "12.0-RELEASE")
+	expectCompare(t, 1, "12.0-RELEASE-p10", "12.0-RELEASE-p9")
+	expectCompare(t, -1, "12.0-RELEASE-p4", "12.0-RELEASE-p10")
+	expectCompare(t, 0, "12.0-RELEASE", "12.0")
+	expectCompare(t, -1, "14.0-ALPHA", "14.0")
+	expectCompare(t, -1, "14.0-ALPHA1", "14.0-RELEASE")
+	expectCompare(t, -1, "14.0-BETA2", "14.0-RC1")
+	expectCompare(t, -1, "14.0-RC3", "14.0-RELEASE")
+	expectCompare(t, 1, "14.0-RELEASE-p1", "14.0-RC3")
+	expectCompare(t, 1, "14.0-RC1", "13.2-RELEASE")
+	expectCompare(t, 0, "22.04.3 LTS", "22.04.3")
+	expectCompare(t, 1, "13.0-RELEASE", "12.4-RELEASE")
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	v, _ := ParseVersion("12.0-RELEASE")
+	twelve, _ := ParseVersion("12")
+	thirteen, _ := ParseVersion("13")
+	if !v.AtLeast(twelve) {
+		t.Error("Expected 12.0-RELEASE to be at least 12")
+	}
+	if v.AtLeast(thirteen) {
+		t.Error("Expected 12.0-RELEASE not to be at least 13")
+	}
+}
+
+func TestParsedVersionPerOS(t *testing.T) {
+	infos := []struct {
+		info     OSInfo
+		expected string
+	}{
+		{OSInfo{Family: "windows", Version: "10.0", Build: "22631"}, "10.0.22631"},
+		{OSInfo{Family: "windows", Version: "6.1"}, "6.1"},
+		{OSInfo{Family: "linux", Version: "12", PointVersion: "12.5"}, "12.5"},
+		{OSInfo{Family: "linux", Version: "3.8.0"}, "3.8.0"},
+		{OSInfo{Family: "darwin", Version: "14.5", Build: "23F79"}, "14.5"},
+		{OSInfo{Family: "freebsd", Version: "12.0-RELEASE", Build: "r341666"}, "12.0-RELEASE"},
+	}
+
+	for _, entry := range infos {
+		v, err := entry.info.ParsedVersion()
+		if err != nil {
+			t.Error(err)
+		}
+		expectEqualStrings(t, entry.expected, v.Original)
+	}
+
+	windows11 := OSInfo{Family: "windows", Version: "10.0", Build: "22000"}
+	v, _ := windows11.ParsedVersion()
+	minimum, _ := ParseVersion("10.0.22000")
+	if !v.AtLeast(minimum) {
+		t.Error("Expected build 22000 to be at least 10.0.22000")
+	}
+}
+
+func TestCompareNatural(t *testing.T) {
+	expectEqualInts(t, 0, compareNatural("el8_10", "el8_10"))
+	expectEqualInts(t, 1, compareNatural("el8_10", "el8_9"))
+	expectEqualInts(t, 0, compareNatural("p010", "p10"))
+	expectEqualInts(t, -1, compareNatural("amd64", "arm64"))
+	expectEqualInts(t, -1, compareNatural("generic", "generic-64k"))
+	expectEqualInts(t, 1, compareNatural("a", ""))
+}
@@ -0,0 +1,342 @@
+package osinfo
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Values of Virtualization.Confidence
+const (
+	// The hypervisor's CPUID signature, or several sources agree (including
+	// CPUID seeing a hypervisor that is only named by DMI)
+	ConfidenceHigh = "high"
+	// A single source other than CPUID
+	ConfidenceMedium = "medium"
+	// Only a hint, such as the hypervisor CPU flag, or nothing found without
+	// being able to check CPUID
+	ConfidenceLow = "low"
+)
+
+// Virtualization describes the hypervisor the OS runs under, if any.
+type Virtualization struct {
+	IsVM bool
+	// The hypervisor as named by systemd-detect-virt --vm, such as "kvm",
+	// "microsoft", "oracle" (VirtualBox), "vm-other" for an unidentified
+	// hypervisor, or "none"
+	ID string
+	// The product, such as "KVM", "Xen HVM", "Hyper-V" or "Firecracker"
+	Product string
+	// ConfidenceHigh, ConfidenceMedium or ConfidenceLow
+	Confidence string
+	// What was found, such as "/sys/class/dmi/id/sys_vendor: QEMU" or
+	// "cpuid: KVMKVMKVM"
+	Evidence []string
+}
+
+// GetVirtualization detects the hypervisor the current system runs under.
+func GetVirtualization() Virtualization {
+	return detectVirtualization(os.DirFS("/"), readHypervisorCPUID())
+}
+
+// GetVirtualizationFromFS detects the hypervisor from the /sys and /proc
+// files under fsys only, without checking CPUID.
+func GetVirtualizationFromFS(fsys fs.FS) Virtualization {
+	return detectVirtualization(fsys, hypervisorCPUID{})
+}
+
+// hypervisorCPUID holds what the CPU reports about the hypervisor.
+type hypervisorCPUID struct {
+	// Whether CPUID could be checked at all
+	available bool
+	// The hypervisor present bit (leaf 1, ECX bit 31)
+	present bool
+	// The vendor signature (leaf 0x40000000)
+	signature string
+	// Running in Hyper-V's root partition, which is the host rather than a VM
+	// (such as Windows with virtualization based security)
+	rootPartition bool
+}
+
+type virtualizationFinding struct {
+	id       string
+	product  string
+	evidence string
+}
+
+// See systemd's src/basic/virt.c for the signatures and DMI strings.
+var cpuidHypervisors = map[string]virtualizationFinding{
+	"XenVMMXenVMM": {id: "xen", product: "Xen"},
+	"KVMKVMKVM":    {id: "kvm", product: "KVM"},
+	// KVM with Hyper-V enlightenments
+	"Linux KVM Hv": {id: "kvm", product: "KVM"},
+	"TCGTCGTCGTCG": {id: "qemu", product: "QEMU"},
+	"VMwareVMware": {id: "vmware", product: "VMware"},
+	"Microsoft Hv": {id: "microsoft", product: "Hyper-V"},
+	"bhyve bhyve ": {id: "bhyve", product: "bhyve"},
+	"QNXQVMBSQG":   {id: "qnx", product: "QNX"},
+	"ACRNACRNACRN": {id: "acrn", product: "ACRN"},
+	"SRESRESRESRE": {id: "sre", product: "SRE"},
+	"VBoxVBoxVBox": {id: "oracle", product: "VirtualBox"},
+	" lrpepyh  vr": {id: "parallels", product: "Parallels"},
+}
+
+var dmiFiles = []string{
+	"sys/class/dmi/id/product_name",
+	"sys/class/dmi/id/sys_vendor",
+	"sys/class/dmi/id/board_vendor",
+	"sys/class/dmi/id/bios_vendor",
+	"sys/class/dmi/id/product_version",
+}
+
+// DMI string prefixes, checked in order against each of dmiFiles
+var dmiHypervisors = []struct {
+	prefix  string
+	finding virtualizationFinding
+}{
+	{"KVM", virtualizationFinding{id: "kvm", product: "KVM"}},
+	{"OpenStack", virtualizationFinding{id: "kvm", product: "KVM"}},
+	{"KubeVirt", virtualizationFinding{id: "kvm", product: "KVM"}},
+	{"Amazon EC2", virtualizationFinding{id: "amazon", product: "Amazon EC2"}},
+	{"QEMU", virtualizationFinding{id: "qemu", product: "QEMU"}},
+	{"VMware", virtualizationFinding{id: "vmware", product: "VMware"}},
+	{"VMW", virtualizationFinding{id: "vmware", product: "VMware"}},
+	{"innotek GmbH", virtualizationFinding{id: "oracle", product: "VirtualBox"}},
+	{"VirtualBox", virtualizationFinding{id: "oracle", product: "VirtualBox"}},
+	{"Oracle Corporation", virtualizationFinding{id: "oracle", product: "VirtualBox"}},
+	{"Xen", virtualizationFinding{id: "xen", product: "Xen HVM"}},
+	{"Bochs", virtualizationFinding{id: "bochs", product: "Bochs"}},
+	{"Parallels", virtualizationFinding{id: "parallels", product: "Parallels"}},
+	{"BHYVE", virtualizationFinding{id: "bhyve", product: "bhyve"}},
+	{"Hyper-V", virtualizationFinding{id: "microsoft", product: "Hyper-V"}},
+	{"Apple Virtualization", virtualizationFinding{id: "apple", product: "Apple Virtualization"}},
+	{"Google Compute Engine", virtualizationFinding{id: "google", product: "Google Compute Engine"}},
+}
+
+// These hypervisors are identified from DMI before CPUID, since they may use
+// KVM or pretend to be Hyper-V.
+var dmiPreferredHypervisors = []string{"oracle", "xen", "amazon", "parallels", "google"}
+
+var deviceTreeHypervisors = []struct {
+	compatible string
+	finding    virtualizationFinding
+}{
+	{"linux,kvm", virtualizationFinding{id: "kvm", product: "KVM"}},
+	{"xen", virtualizationFinding{id: "xen", product: "Xen"}},
+	{"vmware", virtualizationFinding{id: "vmware", product: "VMware"}},
+}
+
+var xenGuestTypes = map[string]string{
+	"PV":  "Xen PV",
+	"HVM": "Xen HVM",
+	"PVH": "Xen PVH",
+}
+
+func detectDMIHypervisor(fsys fs.FS) (finding virtualizationFinding, found bool, hasDMI bool) {
+	values := make(map[string]string)
+	for _, path := range dmiFiles {
+		if value := readSysFile(fsys, path); value != "" {
+			values[path] = value
+			hasDMI = true
+		}
+	}
+
+	for _, path := range dmiFiles {
+		value := values[path]
+		for _, candidate := range dmiHypervisors {
+			if value != "" && strings.HasPrefix(value, candidate.prefix) {
+				finding = candidate.finding
+				finding.evidence = "/" + path + ": " + value
+				return finding, true, hasDMI
+			}
+		}
+	}
+
+	// Hyper-V only names itself in product_version, which is missing on some
+	// versions
+	if values["sys/class/dmi/id/sys_vendor"] == "Microsoft Corporation" && values["sys/class/dmi/id/product_name"] == "Virtual Machine" {
+		return virtualizationFinding{
+			id:       "microsoft",
+			product:  "Hyper-V",
+			evidence: "/sys/class/dmi/id/product_name: Virtual Machine",
+		}, true, hasDMI
+	}
+	return finding, false, hasDMI
+}
+
+// detectXen looks for the Xen interfaces, which are also present in dom0
+// (the host).
+func detectXen(fsys fs.FS) (finding virtualizationFinding, found bool, dom0 bool) {
+	if _, err := fs.Stat(fsys, "proc/xen"); err == nil {
+		finding = virtualizationFinding{id: "xen", product: "Xen", evidence: "/proc/xen exists"}
+		found = true
+	} else if readSysFile(fsys, "sys/hypervisor/type") == "xen" {
+		finding = virtualizationFinding{id: "xen", product: "Xen", evidence: "/sys/hypervisor/type: xen"}
+		found = true
+	}
+	if found {
+		dom0 = strings.Contains(readSysFile(fsys, "proc/xen/capabilities"), "control_d")
+	}
+	return
+}
+
+func detectCPUIDHypervisor(cpu hypervisorCPUID) (finding virtualizationFinding, found bool) {
+	if !cpu.present {
+		return
+	}
+	finding, found = cpuidHypervisors[cpu.signature]
+	if !found {
+		finding = virtualizationFinding{id: "vm-other"}
+	}
+	finding.evidence = "cpuid: " + cpu.signature
+	if cpu.signature == "" {
+		finding.evidence = "cpuid: hypervisor bit"
+	}
+	return finding, found
+}
+
+func detectDeviceTreeHypervisor(fsys fs.FS) (finding virtualizationFinding, found bool) {
+	compatible := readSysFile(fsys, "proc/device-tree/hypervisor/compatible")
+	// The property is a list of NUL terminated strings
+	for _, value := range strings.Split(compatible, "\x00") {
+		for _, candidate := range deviceTreeHypervisors {
+			if value == candidate.compatible {
+				finding = candidate.finding
+				finding.evidence = "/proc/device-tree/hypervisor/compatible: " + value
+				return finding, true
+			}
+		}
+	}
+	return
+}
+
+func hasCPUInfoHypervisorFlag(fsys fs.FS) bool {
+	cpuinfo, err := readTextFile(fsys, "proc/cpuinfo")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(cpuinfo, "\n") {
+		separator := strings.IndexByte(line, ':')
+		if separator < 0 || strings.TrimSpace(line[:separator]) != "flags" {
+			continue
+		}
+		return containsString(strings.Fields(line[separator+1:]), "hypervisor")
+	}
+	return false
+}
+
+// detectVirtualization checks the same sources in the same order as
+// systemd-detect-virt --vm.
+func detectVirtualization(fsys fs.FS, cpu hypervisorCPUID) (virt Virtualization) {
+	dmi, foundDMI, hasDMI := detectDMIHypervisor(fsys)
+	xen, foundXen, xenDom0 := detectXen(fsys)
+	cpuid, foundCPUID := detectCPUIDHypervisor(cpu)
+	hypervisorType := readSysFile(fsys, "sys/hypervisor/type")
+	deviceTree, foundDeviceTree := detectDeviceTreeHypervisor(fsys)
+	cpuFlag := hasCPUInfoHypervisorFlag(fsys)
+
+	var findings []virtualizationFinding
+	for _, candidate := range []struct {
+		finding virtualizationFinding
+		found   bool
+	}{
+		{dmi, foundDMI},
+		{xen, foundXen},
+		{cpuid, cpu.present},
+		{deviceTree, foundDeviceTree},
+	} {
+		if candidate.found {
+			findings = append(findings, candidate.finding)
+			virt.Evidence = append(virt.Evidence, candidate.finding.evidence)
+		}
+	}
+	if hypervisorType != "" && !(foundXen && hypervisorType == "xen") {
+		virt.Evidence = append(virt.Evidence, "/sys/hypervisor/type: "+hypervisorType)
+	}
+	if cpuFlag {
+		virt.Evidence = append(virt.Evidence, "/proc/cpuinfo: hypervisor flag")
+	}
+
+	var chosen virtualizationFinding
+	dmiPreferred := foundDMI && containsString(dmiPreferredHypervisors, dmi.id)
+	switch {
+	case dmiPreferred:
+		chosen = dmi
+	case foundXen && xenDom0:
+		// dom0 is the host
+		virt.Evidence = append(virt.Evidence, "/proc/xen/capabilities: control_d")
+	case foundXen:
+		chosen = xen
+	case cpu.rootPartition:
+		// Hyper-V's root partition is the host
+		virt.Evidence = append(virt.Evidence, "cpuid: Hyper-V root partition")
+	case foundCPUID:
+		chosen = cpuid
+	case foundDMI:
+		chosen = dmi
+	case hypervisorType != "":
+		chosen = virtualizationFinding{id: "vm-other"}
+	case foundDeviceTree:
+		chosen = deviceTree
+	case cpu.present || cpuFlag:
+		chosen = virtualizationFinding{id: "vm-other"}
+	}
+
+	if chosen.id == "" {
+		virt.ID = "none"
+		virt.Confidence = ConfidenceLow
+		if cpu.available || xenDom0 {
+			virt.Confidence = ConfidenceHigh
+		}
+		return
+	}
+
+	virt.IsVM = true
+	virt.ID = chosen.id
+	virt.Product = chosen.product
+	switch virt.ID {
+	case "xen":
+		if guestType := readSysFile(fsys, "sys/hypervisor/guest_type"); guestType != "" {
+			virt.Evidence = append(virt.Evidence, "/sys/hypervisor/guest_type: "+guestType)
+		}
+		virt.Product = xenProduct(fsys, hasDMI)
+	case "kvm":
+		if isFirecracker(fsys, hasDMI) {
+			virt.Product = "Firecracker"
+			virt.Evidence = append(virt.Evidence, "/proc/cmdline: virtio_mmio.device")
+		}
+	}
+
+	agreeing := 0
+	for _, finding := range findings {
+		if finding.id == virt.ID {
+			agreeing++
+		}
+	}
+	switch {
+	case virt.ID == "vm-other":
+		virt.Confidence = ConfidenceLow
+	case agreeing >= 2 || (foundCPUID && cpuid.id == virt.ID) || (dmiPreferred && cpu.present):
+		virt.Confidence = ConfidenceHigh
+	default:
+		virt.Confidence = ConfidenceMedium
+	}
+	return
+}
+
+func xenProduct(fsys fs.FS, hasDMI bool) string {
+	if product, ok := xenGuestTypes[readSysFile(fsys, "sys/hypervisor/guest_type")]; ok {
+		return product
+	}
+	// PV guests have no emulated firmware
+	if hasDMI {
+		return "Xen HVM"
+	}
+	return "Xen PV"
+}
+
+// Firecracker has no firmware, and its virtio devices are passed on the
+// kernel command line.
+func isFirecracker(fsys fs.FS, hasDMI bool) bool {
+	return !hasDMI && strings.Contains(readSysFile(fsys, "proc/cmdline"), "virtio_mmio.device=")
+}
@@ -0,0 +1,218 @@
+package osinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	dmiProductName    = "sys/class/dmi/id/product_name"
+	dmiSysVendor      = "sys/class/dmi/id/sys_vendor"
+	dmiBoardVendor    = "sys/class/dmi/id/board_vendor"
+	dmiBIOSVendor     = "sys/class/dmi/id/bios_vendor"
+	dmiProductVersion = "sys/class/dmi/id/product_version"
+)
+
+func expectVirtualization(t *testing.T, files map[string]string, cpu hypervisorCPUID, id, product, confidence string, evidence ...string) {
+	virt := detectVirtualization(legacyFS(files), cpu)
+	if virt.IsVM != (id != "none") {
+		t.Errorf("Expected IsVM to be %v for [%v]", id != "none", id)
+	}
+	expectEqualStrings(t, id, virt.ID)
+	expectEqualStrings(t, product, virt.Product)
+	expectEqualStrings(t, confidence, virt.Confidence)
+	expectEqualStrings(t, strings.Join(evidence, "; "), strings.Join(virt.Evidence, "; "))
+}
+
+func cpuidSignature(signature string) hypervisorCPUID {
+	return hypervisorCPUID{available: true, present: true, signature: signature}
+}
+
+var bareMetalCPUID = hypervisorCPUID{available: true}
+
+func TestDetectVirtualizationKVM(t *testing.T) {
+	files := map[string]string{
+		dmiProductName: "Standard PC (Q35 + ICH9, 2009)\n",
+		dmiSysVendor:   "QEMU\n",
+		dmiBIOSVendor:  "SeaBIOS\n",
+		"proc/cpuinfo": "processor\t: 0\nflags\t\t: fpu vme de pse tsc msr hypervisor lahf_lm\n",
+	}
+	expectVirtualization(t, files, cpuidSignature("KVMKVMKVM"), "kvm", "KVM", ConfidenceHigh,
+		"/sys/class/dmi/id/sys_vendor: QEMU", "cpuid: KVMKVMKVM", "/proc/cpuinfo: hypervisor flag")
+	// Without CPUID, QEMU is all DMI has to say
+	expectVirtualization(t, files, hypervisorCPUID{}, "qemu", "QEMU", ConfidenceMedium,
+		"/sys/class/dmi/id/sys_vendor: QEMU", "/proc/cpuinfo: hypervisor flag")
+	// Emulated rather than accelerated
+	expectVirtualization(t, files, cpuidSignature("TCGTCGTCGTCG"), "qemu", "QEMU", ConfidenceHigh,
+		"/sys/class/dmi/id/sys_vendor: QEMU", "cpuid: TCGTCGTCGTCG", "/proc/cpuinfo: hypervisor flag")
+
+	// OpenStack names itself in product_name
+	expectVirtualization(t, map[string]string{
+		dmiProductName: "OpenStack Compute",
+		dmiSysVendor:   "OpenStack Foundation",
+	}, cpuidSignature("KVMKVMKVM"), "kvm", "KVM", ConfidenceHigh,
+		"/sys/class/dmi/id/product_name: OpenStack Compute", "cpuid: KVMKVMKVM")
+}
+
+func TestDetectVirtualizationFirecracker(t *testing.T) {
+	// Firecracker has no DMI and uses virtio over MMIO
+	expectVirtualization(t, map[string]string{
+		"proc/cmdline": "console=ttyS0 reboot=k panic=1 pci=off virtio_mmio.device=4K@0xd0000000:5 root=/dev/vda\n",
+	}, cpuidSignature("KVMKVMKVM"), "kvm", "Firecracker", ConfidenceHigh,
+		"cpuid: KVMKVMKVM", "/proc/cmdline: virtio_mmio.device")
+}
+
+func TestDetectVirtualizationCloud(t *testing.T) {
+	// EC2 Nitro instances are KVM underneath
+	expectVirtualization(t, map[string]string{
+		dmiProductName: "m5.large",
+		dmiSysVendor:   "Amazon EC2",
+		dmiBIOSVendor:  "Amazon EC2",
+	}, cpuidSignature("KVMKVMKVM"), "amazon", "Amazon EC2", ConfidenceHigh,
+		"/sys/class/dmi/id/sys_vendor: Amazon EC2", "cpuid: KVMKVMKVM")
+
+	expectVirtualization(t, map[string]string{
+		dmiProductName: "Google Compute Engine",
+		dmiSysVendor:   "Google",
+		dmiBIOSVendor:  "Google",
+	}, cpuidSignature("KVMKVMKVM"), "google", "Google Compute Engine", ConfidenceHigh,
+		"/sys/class/dmi/id/product_name: Google Compute Engine", "cpuid: KVMKVMKVM")
+}
+
+func TestDetectVirtualizationVMware(t *testing.T) {
+	files := map[string]string{
+		dmiProductName: "VMware Virtual Platform",
+		dmiSysVendor:   "VMware, Inc.",
+		dmiBIOSVendor:  "Phoenix Technologies LTD",
+	}
+	expectVirtualization(t, files, cpuidSignature("VMwareVMware"), "vmware", "VMware", ConfidenceHigh,
+		"/sys/class/dmi/id/product_name: VMware Virtual Platform", "cpuid: VMwareVMware")
+	expectVirtualization(t, files, hypervisorCPUID{}, "vmware", "VMware", ConfidenceMedium,
+		"/sys/class/dmi/id/product_name: VMware Virtual Platform")
+}
+
+func TestDetectVirtualizationVirtualBox(t *testing.T) {
+	// VirtualBox's KVM paravirtualization interface must not hide it
+	expectVirtualization(t, map[string]string{
+		dmiProductName: "VirtualBox",
+		dmiSysVendor:   "innotek GmbH",
+		dmiBoardVendor: "Oracle Corporation",
+	}, cpuidSignature("KVMKVMKVM"), "oracle", "VirtualBox", ConfidenceHigh,
+		"/sys/class/dmi/id/product_name: VirtualBox", "cpuid: KVMKVMKVM")
+}
+
+func TestDetectVirtualizationHyperV(t *testing.T) {
+	// Generation 2 VMs name Hyper-V in product_version
+	expectVirtualization(t, map[string]string{
+		dmiProductName:    "Virtual Machine",
+		dmiSysVendor:      "Microsoft Corporation",
+		dmiProductVersion: "Hyper-V UEFI Release v4.1",
+	}, cpuidSignature("Microsoft Hv"), "microsoft", "Hyper-V", ConfidenceHigh,
+		"/sys/class/dmi/id/product_version: Hyper-V UEFI Release v4.1", "cpuid: Microsoft Hv")
+
+	// Generation 1 VMs don't
+	expectVirtualization(t, map[string]string{
+		dmiProductName:    "Virtual Machine",
+		dmiSysVendor:      "Microsoft Corporation",
+		dmiProductVersion: "7.0",
+	}, hypervisorCPUID{}, "microsoft", "Hyper-V", ConfidenceMedium,
+		"/sys/class/dmi/id/product_name: Virtual Machine")
+
+	// The root partition is the host, such as Windows with virtualization
+	// based security or WSL's host
+	root := cpuidSignature("Microsoft Hv")
+	root.rootPartition = true
+	expectVirtualization(t, map[string]string{
+		dmiProductName: "Precision 5570",
+		dmiSysVendor:   "Dell Inc.",
+	}, root, "none", "", ConfidenceHigh,
+		"cpuid: Microsoft Hv", "cpuid: Hyper-V root partition")
+}
+
+func TestDetectVirtualizationXen(t *testing.T) {
+	expectVirtualization(t, map[string]string{
+		"proc/xen/capabilities":     "",
+		"sys/hypervisor/type":       "xen\n",
+		"sys/hypervisor/guest_type": "PV\n",
+	}, hypervisorCPUID{available: true}, "xen", "Xen PV", ConfidenceMedium,
+		"/proc/xen exists", "/sys/hypervisor/guest_type: PV")
+
+	expectVirtualization(t, map[string]string{
+		dmiProductName:              "HVM domU",
+		dmiSysVendor:                "Xen",
+		dmiBIOSVendor:               "Xen",
+		"sys/hypervisor/type":       "xen\n",
+		"sys/hypervisor/guest_type": "HVM\n",
+	}, cpuidSignature("XenVMMXenVMM"), "xen", "Xen HVM", ConfidenceHigh,
+		"/sys/class/dmi/id/sys_vendor: Xen", "/sys/hypervisor/type: xen", "cpuid: XenVMMXenVMM",
+		"/sys/hypervisor/guest_type: HVM")
+
+	// dom0 is the host
+	expectVirtualization(t, map[string]string{
+		"proc/xen/capabilities": "control_d\n",
+		"sys/hypervisor/type":   "xen\n",
+	}, hypervisorCPUID{}, "none", "", ConfidenceHigh,
+		"/proc/xen exists", "/proc/xen/capabilities: control_d")
+}
+
+func TestDetectVirtualizationOthers(t *testing.T) {
+	expectVirtualization(t, map[string]string{
+		dmiProductName: "Parallels Virtual Platform",
+		dmiSysVendor:   "Parallels Software International Inc.",
+	}, cpuidSignature(" lrpepyh  vr"), "parallels", "Parallels", ConfidenceHigh,
+		"/sys/class/dmi/id/product_name: Parallels Virtual Platform", "cpuid:  lrpepyh  vr")
+
+	expectVirtualization(t, map[string]string{
+		dmiProductName: "BHYVE",
+		dmiBIOSVendor:  "BHYVE",
+	}, cpuidSignature("bhyve bhyve "), "bhyve", "bhyve", ConfidenceHigh,
+		"/sys/class/dmi/id/product_name: BHYVE", "cpuid: bhyve bhyve ")
+
+	expectVirtualization(t, map[string]string{}, cpuidSignature("ACRNACRNACRN"), "acrn", "ACRN", ConfidenceHigh,
+		"cpuid: ACRNACRNACRN")
+
+	// Apple silicon Macs, where there's no CPUID
+	expectVirtualization(t, map[string]string{
+		dmiProductName: "Apple Virtualization Generic Platform",
+		dmiSysVendor:   "Apple Inc.",
+	}, hypervisorCPUID{}, "apple", "Apple Virtualization", ConfidenceMedium,
+		"/sys/class/dmi/id/product_name: Apple Virtualization Generic Platform")
+
+	// ARM guests without DMI
+	expectVirtualization(t, map[string]string{
+		"proc/device-tree/hypervisor/compatible": "linux,kvm\x00",
+	}, hypervisorCPUID{}, "kvm", "KVM", ConfidenceMedium,
+		"/proc/device-tree/hypervisor/compatible: linux,kvm")
+}
+
+func TestDetectVirtualizationUnidentified(t *testing.T) {
+	expectVirtualization(t, map[string]string{}, cpuidSignature("NotARealHV!!"), "vm-other", "", ConfidenceLow,
+		"cpuid: NotARealHV!!")
+	expectVirtualization(t, map[string]string{}, hypervisorCPUID{available: true, present: true}, "vm-other", "", ConfidenceLow,
+		"cpuid: hypervisor bit")
+	expectVirtualization(t, map[string]string{
+		"proc/cpuinfo": "processor\t: 0\nflags\t\t: fpu vme hypervisor\n",
+	}, hypervisorCPUID{}, "vm-other", "", ConfidenceLow,
+		"/proc/cpuinfo: hypervisor flag")
+}
+
+func TestDetectVirtualizationBareMetal(t *testing.T) {
+	files := map[string]string{
+		dmiProductName: "PowerEdge R750",
+		dmiSysVendor:   "Dell Inc.",
+		dmiBIOSVendor:  "Dell Inc.",
+		"proc/cpuinfo": "processor\t: 0\nflags\t\t: fpu vme de pse tsc msr\n",
+	}
+	expectVirtualization(t, files, bareMetalCPUID, "none", "", ConfidenceHigh)
+	// Without CPUID a hypervisor could have gone unnoticed
+	expectVirtualization(t, files, hypervisorCPUID{}, "none", "", ConfidenceLow)
+	expectVirtualization(t, map[string]string{}, hypervisorCPUID{}, "none", "", ConfidenceLow)
+}
+
+func TestGetVirtualizationFromFS(t *testing.T) {
+	virt := GetVirtualizationFromFS(legacyFS(map[string]string{
+		dmiSysVendor: "QEMU\n",
+	}))
+	expectEqualStrings(t, "qemu", virt.ID)
+	expectEqualStrings(t, ConfidenceMedium, virt.Confidence)
+}
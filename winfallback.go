@@ -0,0 +1,195 @@
+package osinfo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Fallbacks for when the registry can't be read with reg.exe, for example
+// when it's blocked by AppLocker. They provide less detail than the registry.
+
+// Values of WindowsDetails.ProductType
+const (
+	WindowsProductWorkstation      = "Workstation"
+	WindowsProductDomainController = "Domain Controller"
+	WindowsProductServer           = "Server"
+)
+
+// Win32_OperatingSystem ProductType values
+var cimProductTypes = map[int]string{
+	1: WindowsProductWorkstation,
+	2: WindowsProductDomainController,
+	3: WindowsProductServer,
+}
+
+// windowsFallbacks are tried in order when the registry can't be read.
+var windowsFallbacks = []struct {
+	name string
+	read func(info *OSInfo) error
+}{
+	{"Win32_OperatingSystem", readWindowsCIM},
+	{"systeminfo", readWindowsSystemInfo},
+}
+
+// The fields common to both fallbacks, named after Win32_OperatingSystem
+type windowsFallbackInfo struct {
+	caption        string
+	version        string
+	buildNumber    string
+	servicePack    string
+	osArchitecture string
+	productType    string
+}
+
+// applyWindowsFallback fills in info the same way populateWindowsInfo does
+// from the registry.
+func applyWindowsFallback(info *OSInfo, source string, fallback windowsFallbackInfo) error {
+	var missing []string
+	// Caption has a "Microsoft " prefix, and sometimes a trailing space
+	info.Name = strings.TrimSpace(strings.TrimPrefix(fallback.caption, "Microsoft "))
+	if info.Name == "" {
+		missing = append(missing, "Caption")
+	} else if fallback.servicePack != "" {
+		info.Name = info.Name + " " + fallback.servicePack
+	}
+
+	// Version is the full version, such as "10.0.22631"
+	versionParts := strings.Split(fallback.version, ".")
+	if len(versionParts) >= 2 && versionParts[0] != "" && versionParts[1] != "" {
+		info.Version = versionParts[0] + "." + versionParts[1]
+	} else {
+		missing = append(missing, "Version")
+	}
+
+	info.Build = fallback.buildNumber
+	if info.Build == "" && len(versionParts) >= 3 {
+		info.Build = versionParts[2]
+	}
+	if info.Build == "" {
+		missing = append(missing, "BuildNumber")
+	}
+
+	info.Windows.OSArchitecture = normalizeWindowsArchitecture(fallback.osArchitecture)
+	info.Windows.ProductType = fallback.productType
+	if info.Build != "" {
+		applyWindowsRelease(info)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("Error: Could not read Windows version from %v: missing %v", source, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// normalizeWindowsArchitecture turns the OS architecture reported by Windows,
+// such as "64-bit" or "ARM 64-bit Processor", into "64-bit" or "32-bit".
+func normalizeWindowsArchitecture(architecture string) string {
+	architecture = strings.ToLower(architecture)
+	switch {
+	case strings.Contains(architecture, "64"):
+		return "64-bit"
+	case strings.Contains(architecture, "32"), strings.Contains(architecture, "x86"), strings.HasPrefix(architecture, "arm"):
+		return "32-bit"
+	default:
+		return ""
+	}
+}
+
+// parseWindowsCIM parses the output of
+// `Get-CimInstance Win32_OperatingSystem | ConvertTo-Json`.
+func parseWindowsCIM(info *OSInfo, data []byte) error {
+	var system struct {
+		Caption        string
+		Version        string
+		BuildNumber    string
+		CSDVersion     string
+		OSArchitecture string
+		ProductType    int
+	}
+	// Windows PowerShell may write a byte order mark
+	data = []byte(strings.TrimPrefix(string(data), "\ufeff"))
+	if err := json.Unmarshal(data, &system); err != nil {
+		return fmt.Errorf("Error: Could not parse Win32_OperatingSystem JSON: %v", err)
+	}
+	return applyWindowsFallback(info, "Win32_OperatingSystem", windowsFallbackInfo{
+		caption:        system.Caption,
+		version:        system.Version,
+		buildNumber:    system.BuildNumber,
+		servicePack:    system.CSDVersion,
+		osArchitecture: system.OSArchitecture,
+		productType:    cimProductTypes[system.ProductType],
+	})
+}
+
+// Column positions in `systeminfo /fo csv` output. The headers and most
+// values are localized, but the column order is always the same. There's no
+// OS architecture: System Type is the hardware's, such as "x64-based PC" for
+// 32-bit Windows on a 64-bit processor.
+const (
+	systemInfoOSName          = 1
+	systemInfoOSVersion       = 2
+	systemInfoOSConfiguration = 4
+)
+
+// parseWindowsSystemInfo parses the output of `systeminfo /fo csv`:
+//
+//	"Host Name","OS Name","OS Version","OS Manufacturer","OS Configuration",...
+//	"DESKTOP-1","Microsoft Windows 11 Pro","10.0.22631 N/A Build 22631",...
+func parseWindowsSystemInfo(info *OSInfo, output string) error {
+	reader := csv.NewReader(strings.NewReader(strings.TrimSpace(output)))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("Error: Could not parse systeminfo output: %v", err)
+	}
+	if len(records) < 2 || len(records[1]) <= systemInfoOSConfiguration {
+		return fmt.Errorf("Error: Could not parse systeminfo output: %v", output)
+	}
+	values := records[1]
+
+	// OS Version looks like "10.0.22631 N/A Build 22631", of which only the
+	// version number isn't localized.
+	version := strings.Fields(values[systemInfoOSVersion])
+	fallback := windowsFallbackInfo{
+		caption:     values[systemInfoOSName],
+		productType: systemInfoProductType(values[systemInfoOSConfiguration]),
+	}
+	if len(version) > 0 {
+		fallback.version = version[0]
+	}
+	return applyWindowsFallback(info, "systeminfo", fallback)
+}
+
+// OS Configuration is only understood in English, such as
+// "Standalone Workstation" or "Primary Domain Controller".
+func systemInfoProductType(configuration string) string {
+	switch {
+	case strings.HasSuffix(configuration, "Domain Controller"):
+		return WindowsProductDomainController
+	case strings.HasSuffix(configuration, "Workstation"):
+		return WindowsProductWorkstation
+	case strings.HasSuffix(configuration, "Server"):
+		return WindowsProductServer
+	default:
+		return ""
+	}
+}
+
+func readWindowsCIM(info *OSInfo) error {
+	output, err := readCommandOutput(`C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`, `-NoProfile`, `-NonInteractive`, `-Command`,
+		`Get-CimInstance Win32_OperatingSystem | Select-Object Caption,Version,BuildNumber,CSDVersion,OSArchitecture,ProductType | ConvertTo-Json`)
+	if err != nil {
+		return err
+	}
+	return parseWindowsCIM(info, []byte(output))
+}
+
+func readWindowsSystemInfo(info *OSInfo) error {
+	output, err := readCommandOutput(`C:\Windows\system32\systeminfo.exe`, `/fo`, `csv`)
+	if err != nil {
+		return err
+	}
+	return parseWindowsSystemInfo(info, output)
+}
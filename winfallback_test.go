@@ -0,0 +1,116 @@
+package osinfo
+
+import "testing"
+
+func windowsInfoFromCIM(t *testing.T, fixture string) *OSInfo {
+	info := &OSInfo{ID: "windows"}
+	if err := parseWindowsCIM(info, readFixture(t, fixture)); err != nil {
+		t.Error(err)
+	}
+	return info
+}
+
+func windowsInfoFromSystemInfo(t *testing.T, fixture string) *OSInfo {
+	info := &OSInfo{ID: "windows"}
+	if err := parseWindowsSystemInfo(info, string(readFixture(t, fixture))); err != nil {
+		t.Error(err)
+	}
+	return info
+}
+
+func TestWindowsCIMWindows11(t *testing.T) {
+	info := windowsInfoFromCIM(t, "cim_windows11_pro.json")
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+	expectEqualStrings(t, "10.0", info.Version)
+	expectEqualStrings(t, "22631", info.Build)
+	expectEqualStrings(t, "23H2", info.Codename)
+	expectEqualStrings(t, "10.0.22631", info.Windows.FullBuild)
+	expectEqualStrings(t, "64-bit", info.Windows.OSArchitecture)
+	expectEqualStrings(t, WindowsProductWorkstation, info.Windows.ProductType)
+}
+
+func TestWindowsCIMWindows7(t *testing.T) {
+	info := windowsInfoFromCIM(t, "cim_windows7_sp1.json")
+	expectEqualStrings(t, "Windows 7 Professional Service Pack 1", info.Name)
+	expectEqualStrings(t, "6.1", info.Version)
+	expectEqualStrings(t, "7601", info.Build)
+	expectEqualStrings(t, "", info.Codename)
+	expectEqualStrings(t, "32-bit", info.Windows.OSArchitecture)
+}
+
+func TestWindowsCIMServerFullOutput(t *testing.T) {
+	info := windowsInfoFromCIM(t, "cim_server2019_full.json")
+	expectEqualStrings(t, "Windows Server 2019 Datacenter", info.Name)
+	expectEqualStrings(t, "10.0", info.Version)
+	expectEqualStrings(t, "17763", info.Build)
+	expectEqualStrings(t, "1809", info.Codename)
+	expectEqualStrings(t, "64-bit", info.Windows.OSArchitecture)
+	expectEqualStrings(t, WindowsProductServer, info.Windows.ProductType)
+}
+
+func TestWindowsCIMInvalid(t *testing.T) {
+	info := &OSInfo{}
+	err := parseWindowsCIM(info, []byte(`{"Caption": "Microsoft Windows 11 Pro", "ProductType": 1}`))
+	if err == nil {
+		t.Fatalf("Expected an error for missing values")
+	}
+	expectEqualStrings(t, "Error: Could not read Windows version from Win32_OperatingSystem: missing Version, BuildNumber", err.Error())
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+
+	if err := parseWindowsCIM(&OSInfo{}, []byte("Get-CimInstance : Access denied")); err == nil {
+		t.Errorf("Expected an error for output that isn't JSON")
+	}
+}
+
+func TestWindowsSystemInfoWindows10(t *testing.T) {
+	info := windowsInfoFromSystemInfo(t, "systeminfo_windows10_22h2.csv")
+	expectEqualStrings(t, "Windows 10 Pro", info.Name)
+	expectEqualStrings(t, "10.0", info.Version)
+	expectEqualStrings(t, "19045", info.Build)
+	expectEqualStrings(t, "22H2", info.Codename)
+	expectEqualStrings(t, "", info.Windows.OSArchitecture)
+	expectEqualStrings(t, WindowsProductWorkstation, info.Windows.ProductType)
+}
+
+func TestWindowsSystemInfo32BitOn64BitHardware(t *testing.T) {
+	// System Type is "x64-based PC", which describes the hardware
+	info := windowsInfoFromSystemInfo(t, "systeminfo_windows10_32bit.csv")
+	expectEqualStrings(t, "Windows 10 Home", info.Name)
+	expectEqualStrings(t, "19045", info.Build)
+	expectEqualStrings(t, "", info.Windows.OSArchitecture)
+}
+
+func TestWindowsSystemInfoDomainController(t *testing.T) {
+	info := windowsInfoFromSystemInfo(t, "systeminfo_server2022_dc.csv")
+	expectEqualStrings(t, "Windows Server 2022 Standard", info.Name)
+	expectEqualStrings(t, "20348", info.Build)
+	expectEqualStrings(t, WindowsProductDomainController, info.Windows.ProductType)
+}
+
+func TestWindowsSystemInfoLocalized(t *testing.T) {
+	info := windowsInfoFromSystemInfo(t, "systeminfo_windows11_de.csv")
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+	expectEqualStrings(t, "10.0", info.Version)
+	expectEqualStrings(t, "22631", info.Build)
+	// OS Configuration is localized
+	expectEqualStrings(t, "", info.Windows.ProductType)
+}
+
+func TestWindowsSystemInfoInvalid(t *testing.T) {
+	if err := parseWindowsSystemInfo(&OSInfo{}, "ERROR: Access denied"); err == nil {
+		t.Errorf("Expected an error for output that isn't CSV")
+	}
+	if err := parseWindowsSystemInfo(&OSInfo{}, `"Host Name","OS Name"`+"\n"+`"HOST","Windows"`); err == nil {
+		t.Errorf("Expected an error for missing columns")
+	}
+}
+
+func TestNormalizeWindowsArchitecture(t *testing.T) {
+	expectEqualStrings(t, "64-bit", normalizeWindowsArchitecture("64-bit"))
+	expectEqualStrings(t, "64-bit", normalizeWindowsArchitecture("ARM 64-bit Processor"))
+	expectEqualStrings(t, "64-bit", normalizeWindowsArchitecture("64 bits"))
+	expectEqualStrings(t, "64-bit", normalizeWindowsArchitecture("ARM64-based PC"))
+	expectEqualStrings(t, "32-bit", normalizeWindowsArchitecture("X86-based PC"))
+	expectEqualStrings(t, "32-bit", normalizeWindowsArchitecture("ARM-based PC"))
+	expectEqualStrings(t, "", normalizeWindowsArchitecture(""))
+}
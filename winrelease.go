@@ -0,0 +1,106 @@
+package osinfo
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WindowsDetails holds Windows specific information, mostly from the registry.
+type WindowsDetails struct {
+	// Such as "Professional" or "ServerDatacenter"
+	Edition string
+	// "Client", "Server", "Server Core" or "Nano Server"
+	InstallationType string
+	// The edition the installation was composed from, such as "Enterprise"
+	CompositionEdition string
+	// The update build revision, which identifies the cumulative update
+	UBR int
+	// The feature update, such as "23H2"
+	DisplayVersion string
+	// Version, build and UBR, such as "10.0.22631.3447"
+	FullBuild string
+	// "64-bit" or "32-bit". Only known when read from Win32_OperatingSystem.
+	OSArchitecture string
+	// WindowsProductWorkstation, WindowsProductDomainController or
+	// WindowsProductServer. Only known when read from Win32_OperatingSystem
+	// or systeminfo.
+	ProductType string
+	// Optional registry values that were ignored because they couldn't be
+	// parsed, such as "UBR (d77: Not a hex number)"
+	InvalidValues []string
+}
+
+// The marketing names of Windows releases can't be trusted from the registry:
+// ProductName still says "Windows 10" on Windows 11, and ReleaseID has been
+// frozen at "2009" since 20H2. They are derived from CurrentBuild instead.
+type windowsRelease struct {
+	product string
+	// The server release sharing this build, if any
+	serverProduct  string
+	displayVersion string
+}
+
+var windowsReleases = map[int]windowsRelease{
+	10240: {"Windows 10", "", "1507"},
+	10586: {"Windows 10", "", "1511"},
+	14393: {"Windows 10", "Windows Server 2016", "1607"},
+	15063: {"Windows 10", "", "1703"},
+	16299: {"Windows 10", "", "1709"},
+	17134: {"Windows 10", "", "1803"},
+	17763: {"Windows 10", "Windows Server 2019", "1809"},
+	18362: {"Windows 10", "", "1903"},
+	18363: {"Windows 10", "", "1909"},
+	19041: {"Windows 10", "", "2004"},
+	19042: {"Windows 10", "", "20H2"},
+	19043: {"Windows 10", "", "21H1"},
+	19044: {"Windows 10", "", "21H2"},
+	19045: {"Windows 10", "", "22H2"},
+	20348: {"", "Windows Server 2022", "21H2"},
+	22000: {"Windows 11", "", "21H2"},
+	22621: {"Windows 11", "", "22H2"},
+	22631: {"Windows 11", "", "23H2"},
+	26100: {"Windows 11", "Windows Server 2025", "24H2"},
+	26200: {"Windows 11", "", "25H2"},
+}
+
+var windowsClientProductRE = regexp.MustCompile(`^Windows 1[01]\b`)
+var windowsServerProductRE = regexp.MustCompile(`^Windows Server \d+( R2)?\b`)
+
+func isWindowsServer(info *OSInfo) bool {
+	if info.Windows.InstallationType != "" {
+		return info.Windows.InstallationType != "Client"
+	}
+	return strings.Contains(info.Name, "Server")
+}
+
+// applyWindowsRelease corrects info.Name from info.Build using
+// windowsReleases, fills in the DisplayVersion (also used as the Codename)
+// when the registry doesn't have it, and builds FullBuild.
+func applyWindowsRelease(info *OSInfo) {
+	details := &info.Windows
+	if build, err := strconv.Atoi(info.Build); err == nil {
+		if release, ok := windowsReleases[build]; ok {
+			if isWindowsServer(info) {
+				if release.serverProduct != "" {
+					info.Name = windowsServerProductRE.ReplaceAllString(info.Name, release.serverProduct)
+				}
+			} else if release.product != "" {
+				info.Name = windowsClientProductRE.ReplaceAllString(info.Name, release.product)
+			}
+			if details.DisplayVersion == "" {
+				details.DisplayVersion = release.displayVersion
+			}
+		}
+	}
+
+	if details.DisplayVersion != "" {
+		info.Codename = details.DisplayVersion
+	}
+	if info.Build != "" {
+		details.FullBuild = info.Version + "." + info.Build
+		if details.UBR != 0 {
+			details.FullBuild += "." + strconv.Itoa(details.UBR)
+		}
+	}
+}
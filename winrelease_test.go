@@ -0,0 +1,178 @@
+package osinfo
+
+import "testing"
+
+// Builds OSInfo the way getOSInfoWindows does, from captured reg.exe output
+func windowsInfoFromRegistry(t *testing.T, productNameOutput, currentBuildOutput, releaseIDOutput string) *OSInfo {
+	info := &OSInfo{ID: "windows", Version: "10.0"}
+	var err error
+	if info.Name, err = parseRegistryValues(productNameOutput).getString("ProductName"); err != nil {
+		t.Error(err)
+	}
+	if info.Build, err = parseRegistryValues(currentBuildOutput).getString("CurrentBuild"); err != nil {
+		t.Error(err)
+	}
+	if info.Codename, err = parseRegistryValues(releaseIDOutput).getString("ReleaseId"); err != nil {
+		t.Error(err)
+	}
+	applyWindowsRelease(info)
+	return info
+}
+
+func TestWindows11ReportedAsWindows10(t *testing.T) {
+	info := windowsInfoFromRegistry(t, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ProductName    REG_SZ    Windows 10 Pro
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    CurrentBuild    REG_SZ    22631
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ReleaseId    REG_SZ    2009
+`)
+
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+	expectEqualStrings(t, "23H2", info.Codename)
+	expectEqualStrings(t, "22631", info.Build)
+}
+
+func TestWindows11Enterprise24H2(t *testing.T) {
+	info := windowsInfoFromRegistry(t, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ProductName    REG_SZ    Windows 10 Enterprise
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    CurrentBuild    REG_SZ    26100
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ReleaseId    REG_SZ    2009
+`)
+
+	expectEqualStrings(t, "Windows 11 Enterprise", info.Name)
+	expectEqualStrings(t, "24H2", info.Codename)
+}
+
+func TestWindows10FrozenReleaseID(t *testing.T) {
+	info := windowsInfoFromRegistry(t, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ProductName    REG_SZ    Windows 10 Pro
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    CurrentBuild    REG_SZ    19045
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ReleaseId    REG_SZ    2009
+`)
+
+	expectEqualStrings(t, "Windows 10 Pro", info.Name)
+	expectEqualStrings(t, "22H2", info.Codename)
+}
+
+func TestWindowsServer2025(t *testing.T) {
+	info := windowsInfoFromRegistry(t, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ProductName    REG_SZ    Windows Server 2022 Datacenter
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    CurrentBuild    REG_SZ    26100
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ReleaseId    REG_SZ    2009
+`)
+
+	expectEqualStrings(t, "Windows Server 2025 Datacenter", info.Name)
+	expectEqualStrings(t, "24H2", info.Codename)
+}
+
+func TestWindowsServer2019(t *testing.T) {
+	info := windowsInfoFromRegistry(t, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ProductName    REG_SZ    Windows Server 2019 Standard
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    CurrentBuild    REG_SZ    17763
+`, `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    ReleaseId    REG_SZ    1809
+`)
+
+	expectEqualStrings(t, "Windows Server 2019 Standard", info.Name)
+	expectEqualStrings(t, "1809", info.Codename)
+}
+
+func TestWindowsUnknownBuild(t *testing.T) {
+	info := &OSInfo{Name: "Windows 8.1 Pro", Build: "9600", Codename: ""}
+	applyWindowsRelease(info)
+	expectEqualStrings(t, "Windows 8.1 Pro", info.Name)
+	expectEqualStrings(t, "", info.Codename)
+}
+
+func TestWindowsExtractDetails(t *testing.T) {
+	expectRegistryString(t, "ServerDatacenter", "EditionID", `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    EditionID    REG_SZ    ServerDatacenter
+`)
+	expectRegistryString(t, "Server Core", "InstallationType", `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    InstallationType    REG_SZ    Server Core
+`)
+	expectRegistryString(t, "Enterprise", "CompositionEditionID", `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    CompositionEditionID    REG_SZ    Enterprise
+`)
+	expectRegistryString(t, "23H2", "DisplayVersion", `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    DisplayVersion    REG_SZ    23H2
+`)
+	expectRegistryInt(t, 3447, "UBR", `
+HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion
+    UBR    REG_DWORD    0xd77
+`)
+}
+
+func TestWindowsFullBuild(t *testing.T) {
+	info := &OSInfo{
+		Name:    "Windows 10 Pro",
+		Version: "10.0",
+		Build:   "22631",
+		Windows: WindowsDetails{
+			Edition:            "Professional",
+			InstallationType:   "Client",
+			CompositionEdition: "Enterprise",
+			UBR:                3447,
+			DisplayVersion:     "23H2",
+		},
+	}
+	applyWindowsRelease(info)
+
+	expectEqualStrings(t, "Windows 11 Pro", info.Name)
+	expectEqualStrings(t, "23H2", info.Codename)
+	expectEqualStrings(t, "10.0.22631.3447", info.Windows.FullBuild)
+}
+
+func TestWindowsServerCoreInstallationType(t *testing.T) {
+	// The installation type takes precedence over the product name
+	info := &OSInfo{
+		Name:    "Windows Server 2022 Datacenter",
+		Version: "10.0",
+		Build:   "26100",
+		Windows: WindowsDetails{InstallationType: "Server Core", UBR: 1742},
+	}
+	applyWindowsRelease(info)
+
+	expectEqualStrings(t, "Windows Server 2025 Datacenter", info.Name)
+	expectEqualStrings(t, "24H2", info.Windows.DisplayVersion)
+	expectEqualStrings(t, "10.0.26100.1742", info.Windows.FullBuild)
+}
+
+func TestWindowsFullBuildWithoutUBR(t *testing.T) {
+	info := &OSInfo{
+		Name:    "Windows 11 Pro",
+		Version: "10.0",
+		Build:   "22621",
+		Windows: WindowsDetails{DisplayVersion: "22H2"},
+	}
+	applyWindowsRelease(info)
+	expectEqualStrings(t, "22H2", info.Codename)
+	expectEqualStrings(t, "10.0.22621", info.Windows.FullBuild)
+}